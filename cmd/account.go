@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"ethbaas/internal/ethcomm"
+	"ethbaas/pkg/accountclient"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type AccountCmd struct {
+	argsAddress string
+	argsKey     string
+	argsKeyFile string
+	argsOut     string
+	accountCli  *accountclient.Client
+}
+
+func NewAccountCmd() *AccountCmd {
+	a := &AccountCmd{
+		accountCli: accountclient.NewClient(),
+	}
+	return a
+}
+
+func (a *AccountCmd) rootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account",
+		Short: "Keystore account operations.",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	cmd.AddCommand(a.newCmd())
+	cmd.AddCommand(a.importCmd())
+	cmd.AddCommand(a.listCmd())
+	cmd.AddCommand(a.exportCmd())
+	return cmd
+}
+
+func (a *AccountCmd) newCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new",
+		Short: "Create a new account",
+		Run: func(cmd *cobra.Command, args []string) {
+			pass, err := newPassphrase()
+			if err != nil {
+				log.Fatal(err)
+			}
+			acc, err := a.accountCli.New(pass)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Account %s created.\n", acc.Address.Hex())
+		},
+	}
+	return cmd
+}
+
+func (a *AccountCmd) importCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import a hex private key or keystore file",
+		Run: func(cmd *cobra.Command, args []string) {
+			if (a.argsKey == "") == (a.argsKeyFile == "") {
+				log.Fatal("Either --key or --keyfile is required")
+			}
+
+			var oldPass string
+			if a.argsKeyFile != "" {
+				p, err := ethcomm.Passphrase("Passphrase of key file: ")
+				if err != nil {
+					log.Fatal(err)
+				}
+				oldPass = p
+			}
+			pass, err := newPassphrase()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if a.argsKey != "" {
+				acc, err := a.accountCli.ImportKey(a.argsKey, pass)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Printf("Account %s imported.\n", acc.Address.Hex())
+				return
+			}
+			acc, err := a.accountCli.ImportKeyFile(a.argsKeyFile, oldPass, pass)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Account %s imported.\n", acc.Address.Hex())
+		},
+	}
+	cmd.Flags().StringVarP(&a.argsKey, "key", "k", "", "hex private key file path")
+	cmd.Flags().StringVarP(&a.argsKeyFile, "keyfile", "f", "", "keystore json file path")
+	return cmd
+}
+
+func (a *AccountCmd) listCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List accounts",
+		Run: func(cmd *cobra.Command, args []string) {
+			list := a.accountCli.List()
+			fmt.Println("Account Amount:", len(list))
+			if len(list) != 0 {
+				fmt.Println("Address\t\t\t\t\t\tKeyFile")
+				for _, acc := range list {
+					fmt.Printf("%s\t%s\n", acc.Address.Hex(), acc.URL.Path)
+				}
+			}
+		},
+	}
+	return cmd
+}
+
+func (a *AccountCmd) exportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export an account as keystore json",
+		Run: func(cmd *cobra.Command, args []string) {
+			pass, err := ethcomm.Passphrase("Passphrase: ")
+			if err != nil {
+				log.Fatal(err)
+			}
+			newPass, err := ethcomm.NewPassphrase("Passphrase of exported key: ")
+			if err != nil {
+				log.Fatal(err)
+			}
+			keyJson, err := a.accountCli.Export(a.argsAddress, pass, newPass)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if a.argsOut == "" {
+				fmt.Println(string(keyJson))
+				return
+			}
+			if err := ioutil.WriteFile(a.argsOut, keyJson, 0600); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Account %s exported to %s.\n", a.argsAddress, a.argsOut)
+		},
+	}
+	cmd.Flags().StringVarP(&a.argsAddress, "address", "a", "", "account address")
+	cmd.MarkFlagRequired("address")
+	cmd.Flags().StringVarP(&a.argsOut, "out", "o", "", "output file, stdout when empty")
+	return cmd
+}
+
+// passphrase for a new key, prompted twice on terminal
+func newPassphrase() (string, error) {
+	pass, err := ethcomm.NewPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := os.LookupEnv(ethcomm.NewPassphraseEnv); ok {
+		return pass, nil
+	}
+	confirm, err := ethcomm.NewPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass != confirm {
+		return "", fmt.Errorf("Passphrases do not match")
+	}
+	return pass, nil
+}
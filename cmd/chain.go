@@ -1,17 +1,23 @@
 package cmd
 
 import (
+	"context"
 	"ethbaas/internal/db"
 	"ethbaas/pkg/chainclient"
 	"ethbaas/pkg/projclient"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/spf13/cobra"
 )
 
 type ChainCmd struct {
 	argsName string
+	argsNode int
 	projCli  *projclient.Client
 	chainCli *chainclient.Client
 }
@@ -35,6 +41,7 @@ func (e *ChainCmd) rootCmd() *cobra.Command {
 	cmd.AddCommand(e.infoCmd())
 	cmd.AddCommand(e.podsCmd())
 	cmd.AddCommand(e.clusterCmd())
+	cmd.AddCommand(e.headsCmd())
 	return cmd
 }
 
@@ -43,7 +50,9 @@ func (e *ChainCmd) infoCmd() *cobra.Command {
 		Use:   "info",
 		Short: "chain info",
 		Run: func(cmd *cobra.Command, args []string) {
-			infoes, err := e.chainCli.Info(e.argsName)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			infoes, err := e.chainCli.Info(ctx, e.argsName)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -56,6 +65,19 @@ func (e *ChainCmd) infoCmd() *cobra.Command {
 					info.PeerCount, info.Diff, info.ENode,
 				)
 			}
+
+			state, err := e.chainCli.IndexerState(e.argsName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if state != nil && len(infoes) > 0 {
+				var lag uint64
+				if infoes[0].Highest > state.Number {
+					lag = infoes[0].Highest - state.Number
+				}
+				tf := time.Unix(state.Updated, 0).Format(time.RFC3339)
+				fmt.Printf("Indexer: block %d (%s) updated %s, lag %d\n", state.Number, state.Hash, tf, lag)
+			}
 		},
 	}
 	cmd.Flags().StringVarP(&e.argsName, "name", "n", "", "set project name")
@@ -73,7 +95,9 @@ func (e *ChainCmd) podsCmd() *cobra.Command {
 				log.Fatal(err)
 			}
 
-			list, err := e.chainCli.Pods(proj)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			list, err := e.chainCli.Pods(ctx, proj)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -100,7 +124,9 @@ func (e *ChainCmd) clusterCmd() *cobra.Command {
 				log.Fatal(err)
 			}
 
-			if err := e.chainCli.Cluster(proj); err != nil {
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			if err := e.chainCli.Cluster(ctx, proj); err != nil {
 				log.Fatal(err)
 			}
 			fmt.Println("Nodes has connected to a cluster.")
@@ -110,3 +136,29 @@ func (e *ChainCmd) clusterCmd() *cobra.Command {
 	cmd.MarkFlagRequired("name")
 	return cmd
 }
+
+func (e *ChainCmd) headsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "heads",
+		Short: "Follow new chain heads through websocket.",
+		Run: func(cmd *cobra.Command, args []string) {
+			// follow heads until interrupted, --timeout is not applied
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+			fmt.Println("Number\tGasUsed\tTime\t\t\tHash")
+			err := e.chainCli.WatchHeads(ctx, e.argsName, e.argsNode, func(h *types.Header) {
+				t := time.Unix(int64(h.Time), 0)
+				fmt.Printf("%d\t%d\t%s\t%s\n",
+					h.Number, h.GasUsed, t.Format(time.RFC3339), h.Hash().Hex(),
+				)
+			})
+			if err != nil && err != context.Canceled {
+				log.Fatal(err)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&e.argsName, "name", "n", "", "set project name")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().IntVarP(&e.argsNode, "node", "", 0, "node index to subscribe")
+	return cmd
+}
@@ -1,27 +1,45 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
 	"ethbaas/internal/model"
+	"ethbaas/internal/solc"
 	"ethbaas/pkg/contractclient"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
 type ContractCmd struct {
-	argsName    string
-	argsProj    string
-	argsKey     string
-	argsValue   string
-	argsMethod  string
-	argsInputs  string
-	argsABI     string
-	argsBIN     string
-	contractCli *contractclient.Client
+	txArgs
+	argsName      string
+	argsProj      string
+	argsKey       string
+	argsValue     string
+	argsMethod    string
+	argsInputs    string
+	argsArgs      string
+	argsABI       string
+	argsBIN       string
+	argsSol       string
+	argsAddress   string
+	argsEvent     string
+	argsFilters   []string
+	argsFromBlock int64
+	argsToBlock   int64
+	argsContract  string
+	argsNode      int
+	argsVersion   int
+	argsOutput    string
+	argsText      bool
+	contractCli   *contractclient.Client
 }
 
 func NewContractCmd(dbClient *db.Client) *ContractCmd {
@@ -43,6 +61,10 @@ func (c *ContractCmd) rootCmd() *cobra.Command {
 	cmd.AddCommand(c.deployCmd())
 	cmd.AddCommand(c.queryCmd())
 	cmd.AddCommand(c.writeCmd())
+	cmd.AddCommand(c.importCmd())
+	cmd.AddCommand(c.eventsCmd())
+	cmd.AddCommand(c.versionsCmd())
+	cmd.AddCommand(c.useCmd())
 	return cmd
 }
 
@@ -51,18 +73,19 @@ func (c *ContractCmd) listCmd() *cobra.Command {
 		Use:   "list",
 		Short: "List contract",
 		Run: func(cmd *cobra.Command, args []string) {
-			_, list, err := c.contractCli.List()
+			_, list, err := c.contractCli.List(c.argsProj)
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Println("Name\tProject\tAddress\t\t\t\t\t\tCreated")
+			fmt.Println("Name\tProject\tVersion\tAddress\t\t\t\t\t\tCreated")
 			for _, item := range list {
 				t := time.Unix(item.Created, 0)
 				tf := t.Format(time.RFC3339)
-				fmt.Printf("%s\t%s\t%s\t%s\n", item.Name, item.Proj, item.Address, tf)
+				fmt.Printf("%s\t%s\t%d\t%s\t%s\n", item.Name, item.Proj, item.Version, item.Address, tf)
 			}
 		},
 	}
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name, all projects when empty")
 	return cmd
 }
 
@@ -71,35 +94,179 @@ func (c *ContractCmd) deployCmd() *cobra.Command {
 		Use:   "deploy",
 		Short: "deploy contract",
 		Run: func(cmd *cobra.Command, args []string) {
-			abiB, err := ioutil.ReadFile(c.argsABI)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			cont, err := c.loadContract(ctx)
 			if err != nil {
 				log.Fatal(err)
 			}
-			binB, err := ioutil.ReadFile(c.argsBIN)
+			txOpts := c.txOptions()
+			if c.argsValue != "" {
+				value, ok := new(big.Int).SetString(c.argsValue, 0)
+				if !ok || value.Sign() < 0 {
+					log.Fatalf("Invalid value: %s", c.argsValue)
+				}
+				txOpts.Value = value
+			}
+			res, err := c.contractCli.Deploy(ctx, c.argsProj, cont, c.argsArgs, c.argsNode, txOpts)
+			printDeployResult(c.argsName, res)
 			if err != nil {
 				log.Fatal(err)
 			}
+		},
+	}
+	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name")
+	cmd.MarkFlagRequired("proj")
+	cmd.Flags().StringVarP(&c.argsABI, "abi", "a", "", "contract abi path ")
+	cmd.Flags().StringVarP(&c.argsBIN, "bin", "b", "", "contract bin path ")
+	cmd.Flags().StringVarP(&c.argsSol, "sol", "", "", "solidity source path, compiled with solc instead of --abi and --bin")
+	cmd.Flags().StringVarP(&c.argsContract, "contract", "", "", "contract to deploy from --sol, defaults to the file name")
+	cmd.Flags().StringVarP(&c.argsArgs, "args", "", "", "constructor arguments: a JSON array or comma separated values")
+	cmd.Flags().StringVarP(&c.argsValue, "value", "", "", "wei sent to a payable constructor")
+	cmd.Flags().IntVarP(&c.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	c.addTxFlags(cmd, true, "wait for the receipt, contract is registered once mined")
+	return cmd
+}
 
+func (c *ContractCmd) importCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Register a contract deployed outside ethbaas",
+		Run: func(cmd *cobra.Command, args []string) {
+			abiB, err := ioutil.ReadFile(c.argsABI)
+			if err != nil {
+				log.Fatal(err)
+			}
 			cont := &model.Contract{
 				Name: c.argsName,
 				ABI:  string(abiB),
-				BIN:  string(binB),
 			}
-			addr, err := c.contractCli.Deploy(c.argsProj, cont)
+			if c.argsBIN != "" {
+				binB, err := ioutil.ReadFile(c.argsBIN)
+				if err != nil {
+					log.Fatal(err)
+				}
+				cont.BIN = string(binB)
+			}
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			dbcont, err := c.contractCli.Import(ctx, c.argsProj, cont, c.argsAddress, c.argsNode)
 			if err != nil {
 				log.Fatal(err)
 			}
-			_ = addr
+			fmt.Printf("Contract %s imported as version %d.\n", dbcont.Name, dbcont.Version)
 		},
 	}
 	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
 	cmd.MarkFlagRequired("name")
 	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name")
 	cmd.MarkFlagRequired("proj")
+	cmd.Flags().StringVarP(&c.argsAddress, "address", "", "", "contract address")
+	cmd.MarkFlagRequired("address")
 	cmd.Flags().StringVarP(&c.argsABI, "abi", "a", "", "contract abi path ")
 	cmd.MarkFlagRequired("abi")
-	cmd.Flags().StringVarP(&c.argsBIN, "bin", "b", "", "contract bin path ")
-	cmd.MarkFlagRequired("bin")
+	cmd.Flags().StringVarP(&c.argsBIN, "bin", "b", "", "optional bytecode path, checked against the code at address")
+	cmd.Flags().IntVarP(&c.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	return cmd
+}
+
+func (c *ContractCmd) eventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Query decoded contract events",
+		Run: func(cmd *cobra.Command, args []string) {
+			q := model.EventQuery{
+				Event:         c.argsEvent,
+				Filters:       c.argsFilters,
+				FromBlock:     big.NewInt(c.argsFromBlock),
+				BytesAsString: c.argsText,
+			}
+			if c.argsToBlock >= 0 {
+				q.ToBlock = big.NewInt(c.argsToBlock)
+			}
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			events, err := c.contractCli.Events(ctx, c.argsProj, c.argsName, q, c.argsNode)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			switch c.argsOutput {
+			case "json":
+				out, err := json.MarshalIndent(events, "", "  ")
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(string(out))
+			case "text":
+				for _, e := range events {
+					fmt.Printf("%s block %d log %d tx %s\n", e.Event, e.BlockNumber, e.LogIndex, e.TxHash)
+					printFields(e.Args, "  ")
+				}
+			default:
+				log.Fatalf("Unknown output format: %s", c.argsOutput)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name, required when the contract name is not unique")
+	cmd.Flags().StringVarP(&c.argsEvent, "event", "e", "", "event name, all events when empty")
+	cmd.Flags().Int64VarP(&c.argsFromBlock, "from-block", "", 0, "first block")
+	cmd.Flags().Int64VarP(&c.argsToBlock, "to-block", "", -1, "last block, latest when negative")
+	cmd.Flags().StringArrayVarP(&c.argsFilters, "filter", "f", nil, "indexed argument filter name=value of --event, repeat a name to match any value")
+	cmd.Flags().IntVarP(&c.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	cmd.Flags().StringVarP(&c.argsOutput, "output", "o", "json", "output format: text or json")
+	cmd.Flags().BoolVarP(&c.argsText, "bytes-as-string", "", false, "render fixed bytes like bytes32 as trimmed text instead of hex")
+	return cmd
+}
+
+func (c *ContractCmd) versionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "List deployed versions of a contract",
+		Run: func(cmd *cobra.Command, args []string) {
+			list, err := c.contractCli.Versions(c.argsProj, c.argsName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("Version\tActive\tAddress\t\t\t\t\t\tCompiler\tCreated")
+			for _, item := range list {
+				tf := time.Unix(item.Created, 0).Format(time.RFC3339)
+				active := ""
+				if item.Active {
+					active = "*"
+				}
+				fmt.Printf("%d\t%s\t%s\t%s\t%s\n", item.Version, active, item.Address, item.Compiler, tf)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name")
+	cmd.MarkFlagRequired("proj")
+	return cmd
+}
+
+func (c *ContractCmd) useCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use",
+		Short: "Switch the active version of a contract",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := c.contractCli.Use(c.argsProj, c.argsName, c.argsVersion); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Contract %s now uses version %d.\n", c.argsName, c.argsVersion)
+		},
+	}
+	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name")
+	cmd.MarkFlagRequired("proj")
+	cmd.Flags().IntVarP(&c.argsVersion, "version", "", 0, "contract version")
+	cmd.MarkFlagRequired("version")
 	return cmd
 }
 
@@ -108,19 +275,37 @@ func (c *ContractCmd) queryCmd() *cobra.Command {
 		Use:   "query",
 		Short: "query contract",
 		Run: func(cmd *cobra.Command, args []string) {
-			res, err := c.contractCli.Query(c.argsName, c.argsMethod, c.argsInputs)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			res, err := c.contractCli.Query(ctx, c.argsProj, c.argsName, c.argsMethod, c.argsInputs, c.argsNode, c.argsText)
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			fmt.Printf("Call method %s return: %s\n", c.argsMethod, res)
+			switch c.argsOutput {
+			case "json":
+				out, err := json.MarshalIndent(res, "", "  ")
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(string(out))
+			case "text":
+				fmt.Printf("Call method %s return:\n", c.argsMethod)
+				printFields(res, "  ")
+			default:
+				log.Fatalf("Unknown output format: %s", c.argsOutput)
+			}
 		},
 	}
 	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
 	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name, required when the contract name is not unique")
 	cmd.Flags().StringVarP(&c.argsMethod, "method", "m", "", "contract method")
 	cmd.MarkFlagRequired("method")
-	cmd.Flags().StringVarP(&c.argsInputs, "input", "i", "", "contract method inputs: a,b")
+	cmd.Flags().StringVarP(&c.argsInputs, "input", "i", "", "method inputs: a JSON array or comma separated values, e.g. 0x12..,\"a, b\",[1,2]")
+	cmd.Flags().IntVarP(&c.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	cmd.Flags().StringVarP(&c.argsOutput, "output", "o", "text", "output format: text or json")
+	cmd.Flags().BoolVarP(&c.argsText, "bytes-as-string", "", false, "render fixed bytes like bytes32 as trimmed text instead of hex")
 	return cmd
 }
 
@@ -129,18 +314,103 @@ func (c *ContractCmd) writeCmd() *cobra.Command {
 		Use:   "write",
 		Short: "Write to contarct",
 		Run: func(cmd *cobra.Command, args []string) {
-			tx, err := c.contractCli.Write(c.argsName, c.argsMethod, c.argsInputs)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			res, err := c.contractCli.Write(ctx, c.argsProj, c.argsName, c.argsMethod, c.argsInputs, c.argsNode, c.txOptions())
+			printTxResult(res)
 			if err != nil {
 				log.Fatal(err)
 			}
-
-			fmt.Printf("Tx sent: %s\n", tx)
 		},
 	}
 	cmd.Flags().StringVarP(&c.argsName, "name", "n", "", "contract name")
 	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&c.argsProj, "proj", "p", "", "project name, required when the contract name is not unique")
 	cmd.Flags().StringVarP(&c.argsMethod, "method", "m", "", "contract method")
 	cmd.MarkFlagRequired("method")
-	cmd.Flags().StringVarP(&c.argsInputs, "input", "i", "", "contract method inputs: a,b")
+	cmd.Flags().StringVarP(&c.argsInputs, "input", "i", "", "method inputs: a JSON array or comma separated values, e.g. 0x12..,\"a, b\",[1,2]")
+	cmd.Flags().IntVarP(&c.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	c.addTxFlags(cmd, false, "wait for the receipt")
 	return cmd
 }
+
+// contract to deploy, compiled from --sol or read from --abi and --bin
+func (c *ContractCmd) loadContract(ctx context.Context) (*model.Contract, error) {
+	if c.argsSol != "" {
+		if c.argsABI != "" || c.argsBIN != "" {
+			return nil, fmt.Errorf("--sol cannot be used with --abi or --bin")
+		}
+		cont, err := solc.CompileContract(ctx, solc.OptionsFromConfig(), c.argsSol, c.argsContract)
+		if err != nil {
+			return nil, err
+		}
+		cont.Name = c.argsName
+		return cont, nil
+	}
+	if c.argsABI == "" || c.argsBIN == "" {
+		return nil, fmt.Errorf("Either --sol or both --abi and --bin are required")
+	}
+	abiB, err := ioutil.ReadFile(c.argsABI)
+	if err != nil {
+		return nil, err
+	}
+	binB, err := ioutil.ReadFile(c.argsBIN)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Contract{
+		Name: c.argsName,
+		ABI:  string(abiB),
+		BIN:  string(binB),
+	}, nil
+}
+
+// print decoded values, tuples are nested by indent
+func printFields(fields model.Fields, indent string) {
+	for _, f := range fields {
+		switch v := f.Value.(type) {
+		case model.Fields:
+			fmt.Printf("%s%s (%s):\n", indent, f.Name, f.Type)
+			printFields(v, indent+"  ")
+		case []interface{}:
+			out, _ := json.Marshal(v)
+			fmt.Printf("%s%s (%s): %s\n", indent, f.Name, f.Type, out)
+		default:
+			fmt.Printf("%s%s (%s): %v\n", indent, f.Name, f.Type, v)
+		}
+	}
+}
+
+// print sent tx and its receipt once mined
+func printTxResult(res *model.TxResult) {
+	if res == nil {
+		return
+	}
+	fmt.Println("Tx sent:", res.Hash)
+	if !res.Mined {
+		return
+	}
+	status := "success"
+	if res.Failed() {
+		status = "failed"
+	}
+	fmt.Printf("Block: %d\tGasUsed: %d\tStatus: %s\n", res.BlockNumber, res.GasUsed, status)
+	if res.RevertReason != "" {
+		fmt.Println("Revert reason:", res.RevertReason)
+	}
+}
+
+// print deployment tx and registration state of the contract
+func printDeployResult(name string, res *model.TxResult) {
+	if res == nil {
+		return
+	}
+	printTxResult(res)
+	fmt.Println("Contract address:", res.Contract)
+	switch {
+	case !res.Mined:
+		fmt.Printf("Contract %s is registered once the deployment is mined, see tx wait.\n", name)
+	case !res.Failed():
+		fmt.Printf("Contract %s deployed.\n", name)
+	}
+}
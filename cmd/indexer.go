@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"ethbaas/internal/db"
+	"ethbaas/pkg/indexclient"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+type IndexerCmd struct {
+	argsProj      string
+	argsFromBlock uint64
+	indexCli      *indexclient.Client
+}
+
+func NewIndexerCmd(db *db.Client) *IndexerCmd {
+	i := &IndexerCmd{
+		indexCli: indexclient.NewClient(db),
+	}
+	return i
+}
+
+func (i *IndexerCmd) rootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "indexer",
+		Short: "Chain Indexer Operations.",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	cmd.AddCommand(i.startCmd())
+	return cmd
+}
+
+func (i *IndexerCmd) startCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Index blocks, txs and logs of a project chain into the database",
+		Run: func(cmd *cobra.Command, args []string) {
+			// index until interrupted, --timeout is not applied
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+			fmt.Println("Indexing project", i.argsProj)
+			err := i.indexCli.Run(ctx, i.argsProj, i.argsFromBlock)
+			if err != nil && err != context.Canceled {
+				log.Fatal(err)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&i.argsProj, "proj", "p", "", "project name")
+	cmd.MarkFlagRequired("proj")
+	cmd.Flags().Uint64VarP(&i.argsFromBlock, "from-block", "", 0, "first block to index, ignored when resuming")
+	return cmd
+}
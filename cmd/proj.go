@@ -37,6 +37,7 @@ func (p *ProjCmd) rootCmd() *cobra.Command {
 	cmd.AddCommand(p.startCmd())
 	cmd.AddCommand(p.stopCmd())
 	cmd.AddCommand(p.deleteCmd())
+	cmd.AddCommand(p.scaleCmd())
 	return cmd
 }
 
@@ -139,3 +140,28 @@ func (p *ProjCmd) deleteCmd() *cobra.Command {
 	cmd.MarkFlagRequired("name")
 	return cmd
 }
+
+func (p *ProjCmd) scaleCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "scale",
+		Short: "Scale a project to a node count",
+		Run: func(cmd *cobra.Command, args []string) {
+			dbproj, err := p.pcli.Get(p.argsName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := p.pcli.Scale(p.argsName, p.argsNodeCount); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Project %s scaled to %d nodes.\n", p.argsName, p.argsNodeCount)
+			if dbproj.Running && p.argsNodeCount > dbproj.NodeCount {
+				fmt.Printf("Peer added nodes once up with 'ethbaas chain cluster -n %s'.\n", p.argsName)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&p.argsName, "name", "n", "", "set project name")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().IntVarP(&p.argsNodeCount, "nodeCount", "c", 0, "set node count")
+	cmd.MarkFlagRequired("nodeCount")
+	return cmd
+}
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"ethbaas/internal/db"
+	"ethbaas/internal/model"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -14,6 +18,9 @@ type Command struct {
 	chainCmd    *ChainCmd
 	contractCmd *ContractCmd
 	storeCmd    *StoreCmd
+	accountCmd  *AccountCmd
+	txCmd       *TxCmd
+	indexerCmd  *IndexerCmd
 	serverCmd   *ServerCmd
 }
 
@@ -33,6 +40,7 @@ func (c *Command) setup() {
 			cmd.Help()
 		},
 	}
+	rootCmd.PersistentFlags().Duration("timeout", time.Minute, "timeout of chain operations, 0 disables it")
 	c.rootCmd = rootCmd
 
 	projCmd := newProjCmd(c.dbClient)
@@ -51,6 +59,18 @@ func (c *Command) setup() {
 	rootCmd.AddCommand(storeCmd.rootCmd())
 	c.storeCmd = storeCmd
 
+	accountCmd := NewAccountCmd()
+	rootCmd.AddCommand(accountCmd.rootCmd())
+	c.accountCmd = accountCmd
+
+	txCmd := NewTxCmd(c.dbClient)
+	rootCmd.AddCommand(txCmd.rootCmd())
+	c.txCmd = txCmd
+
+	indexerCmd := NewIndexerCmd(c.dbClient)
+	rootCmd.AddCommand(indexerCmd.rootCmd())
+	c.indexerCmd = indexerCmd
+
 	serverCmd := NewServerCmd()
 	rootCmd.AddCommand(serverCmd.rootCmd())
 	c.serverCmd = serverCmd
@@ -61,3 +81,43 @@ func (c *Command) Execute() {
 		os.Exit(1)
 	}
 }
+
+// command context, cancelled on interrupt or when --timeout expires
+func cmdContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// flags of commands sending txs
+type txArgs struct {
+	argsGasLimit uint64
+	argsWait     bool
+	argsLegacy   bool
+	argsFrom     string
+}
+
+// add tx flags to cmd, wait is the default of --wait
+func (t *txArgs) addTxFlags(cmd *cobra.Command, wait bool, waitUsage string) {
+	cmd.Flags().StringVarP(&t.argsFrom, "from", "", "", "sending keystore account, default account when empty")
+	cmd.Flags().BoolVarP(&t.argsLegacy, "legacy", "", false, "send legacy gas price tx instead of EIP-1559")
+	cmd.Flags().BoolVarP(&t.argsWait, "wait", "w", wait, waitUsage)
+	cmd.Flags().Uint64VarP(&t.argsGasLimit, "gas-limit", "", 0, "gas limit, estimated when 0")
+}
+
+// tx options from flags
+func (t *txArgs) txOptions() model.TxOptions {
+	return model.TxOptions{
+		From:     t.argsFrom,
+		Legacy:   t.argsLegacy,
+		GasLimit: t.argsGasLimit,
+		Wait:     t.argsWait,
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
 	"ethbaas/pkg/contractclient"
 	"fmt"
 	"log"
@@ -10,12 +11,14 @@ import (
 )
 
 type StoreCmd struct {
+	txArgs
 	storeCli  *contractclient.StoreClient
 	argsName  string
 	argsProj  string
 	argsPath  string
 	argsKey   string
 	argsValue string
+	argsNode  int
 }
 
 func NewStoreCmd(db *db.Client) *StoreCmd {
@@ -45,10 +48,13 @@ func (s *StoreCmd) deployCmd() *cobra.Command {
 		Use:   "deploy",
 		Short: "Deploy store contract",
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := s.storeCli.Deploy(s.argsProj, s.argsName, s.argsPath); err != nil {
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			res, err := s.storeCli.Deploy(ctx, s.argsProj, s.argsName, s.argsPath, s.argsNode, s.txOptions())
+			printDeployResult(s.argsName, res)
+			if err != nil {
 				log.Fatal(err)
 			}
-			log.Printf("Contract %s deployed.\n", s.argsName)
 		},
 	}
 	cmd.Flags().StringVarP(&s.argsProj, "proj", "p", "", "project name")
@@ -56,6 +62,8 @@ func (s *StoreCmd) deployCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&s.argsPath, "path", "", "", "contract path ")
 	cmd.MarkFlagRequired("path")
+	cmd.Flags().IntVarP(&s.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	s.addTxFlags(cmd, true, "wait for the receipt, contract is registered once mined")
 	return cmd
 }
 
@@ -64,15 +72,19 @@ func (s *StoreCmd) queryCmd() *cobra.Command {
 		Use:   "query",
 		Short: "Query store contract",
 		Run: func(cmd *cobra.Command, args []string) {
-			value, err := s.storeCli.Query(s.argsName, s.argsKey)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			value, err := s.storeCli.Query(ctx, s.argsProj, s.argsName, s.argsKey, s.argsNode)
 			if err != nil {
 				log.Fatal(err)
 			}
 			fmt.Println("Query contract result:", value)
 		},
 	}
+	cmd.Flags().StringVarP(&s.argsProj, "proj", "p", "", "project name, required when several projects have a store")
 	cmd.Flags().StringVarP(&s.argsKey, "key", "k", "", "contract key")
 	cmd.MarkFlagRequired("key")
+	cmd.Flags().IntVarP(&s.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
 	return cmd
 }
 
@@ -81,16 +93,21 @@ func (s *StoreCmd) writeCmd() *cobra.Command {
 		Use:   "write",
 		Short: "write to store contract",
 		Run: func(cmd *cobra.Command, args []string) {
-			tx, err := s.storeCli.Write(s.argsName, s.argsKey, s.argsValue)
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			res, err := s.storeCli.Write(ctx, s.argsProj, s.argsName, s.argsKey, s.argsValue, s.argsNode, s.txOptions())
+			printTxResult(res)
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Println("Contract writed, tx:", tx)
 		},
 	}
+	cmd.Flags().StringVarP(&s.argsProj, "proj", "p", "", "project name, required when several projects have a store")
 	cmd.Flags().StringVarP(&s.argsKey, "key", "k", "", "contract key")
 	cmd.MarkFlagRequired("key")
 	cmd.Flags().StringVarP(&s.argsValue, "value", "v", "", "contract value")
 	cmd.MarkFlagRequired("value")
+	cmd.Flags().IntVarP(&s.argsNode, "node", "", ethcomm.AnyNode, "pin node index, selected automatically by default")
+	s.addTxFlags(cmd, false, "wait for the receipt")
 	return cmd
 }
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"ethbaas/internal/db"
+	"ethbaas/pkg/txclient"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type TxCmd struct {
+	argsProj   string
+	argsStatus string
+	argsHash   string
+	txCli      *txclient.Client
+}
+
+func NewTxCmd(db *db.Client) *TxCmd {
+	t := &TxCmd{
+		txCli: txclient.NewClient(db),
+	}
+	return t
+}
+
+func (t *TxCmd) rootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tx",
+		Short: "Transaction Operations.",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	cmd.AddCommand(t.listCmd())
+	cmd.AddCommand(t.showCmd())
+	cmd.AddCommand(t.waitCmd())
+	cmd.AddCommand(t.resendCmd())
+	cmd.AddCommand(t.speedupCmd())
+	cmd.AddCommand(t.cancelCmd())
+	return cmd
+}
+
+func (t *TxCmd) listCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List sent transactions",
+		Run: func(cmd *cobra.Command, args []string) {
+			_, list, err := t.txCli.List(t.argsProj, t.argsStatus)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("Hash\t\t\t\t\t\t\t\t\tProject\tContract\tMethod\tNonce\tStatus\tCreated")
+			for _, item := range list {
+				tf := time.Unix(item.Created, 0).Format(time.RFC3339)
+				fmt.Printf("%s\t%s\t%s\t%s\t%d\t%s\t%s\n", item.Hash, item.Proj, item.Contract, item.Method, item.Nonce, item.Status, tf)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&t.argsProj, "proj", "p", "", "project name, all projects when empty")
+	cmd.Flags().StringVarP(&t.argsStatus, "status", "s", "", "tx status: pending, success, failed, dropped or replaced")
+	return cmd
+}
+
+func (t *TxCmd) showCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show a sent transaction",
+		Run: func(cmd *cobra.Command, args []string) {
+			tx, err := t.txCli.Get(t.argsHash)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printTx(tx)
+		},
+	}
+	cmd.Flags().StringVarP(&t.argsHash, "hash", "", "", "tx hash")
+	cmd.MarkFlagRequired("hash")
+	return cmd
+}
+
+func (t *TxCmd) waitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for a sent transaction to be mined",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			tx, err := t.txCli.Wait(ctx, t.argsHash)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printTx(tx)
+		},
+	}
+	cmd.Flags().StringVarP(&t.argsHash, "hash", "", "", "tx hash")
+	cmd.MarkFlagRequired("hash")
+	return cmd
+}
+
+func (t *TxCmd) resendCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resend",
+		Short: "Broadcast a pending or dropped transaction again",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			if err := t.txCli.Resend(ctx, t.argsHash); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("Tx resent:", t.argsHash)
+		},
+	}
+	cmd.Flags().StringVarP(&t.argsHash, "hash", "", "", "tx hash")
+	cmd.MarkFlagRequired("hash")
+	return cmd
+}
+
+func (t *TxCmd) speedupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "speedup",
+		Short: "Resubmit a pending transaction with bumped fees",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			tx, err := t.txCli.Speedup(ctx, t.argsHash)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Tx %s replaced by %s\n", tx.Replaces, tx.Hash)
+		},
+	}
+	cmd.Flags().StringVarP(&t.argsHash, "hash", "", "", "tx hash")
+	cmd.MarkFlagRequired("hash")
+	return cmd
+}
+
+func (t *TxCmd) cancelCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel a pending transaction by a zero value transfer to self",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := cmdContext(cmd)
+			defer cancel()
+			tx, err := t.txCli.Cancel(ctx, t.argsHash)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Tx %s cancelled by %s\n", tx.Replaces, tx.Hash)
+		},
+	}
+	cmd.Flags().StringVarP(&t.argsHash, "hash", "", "", "tx hash")
+	cmd.MarkFlagRequired("hash")
+	return cmd
+}
+
+// print recorded tx
+func printTx(tx *db.Transaction) {
+	fmt.Println("Hash:", tx.Hash)
+	fmt.Println("Project:", tx.Proj)
+	fmt.Println("Contract:", tx.Contract)
+	fmt.Println("Method:", tx.Method)
+	fmt.Println("Args:", tx.Args)
+	fmt.Println("From:", tx.From)
+	fmt.Println("Nonce:", tx.Nonce)
+	fmt.Println("Status:", tx.Status)
+	if tx.BlockNumber > 0 {
+		fmt.Printf("Block: %d\tGasUsed: %d\n", tx.BlockNumber, tx.GasUsed)
+	}
+	if tx.ContractAddress != "" {
+		fmt.Println("Contract address:", tx.ContractAddress)
+	}
+	if tx.RevertReason != "" {
+		fmt.Println("Revert reason:", tx.RevertReason)
+	}
+	if tx.Replaces != "" {
+		fmt.Println("Replaces:", tx.Replaces)
+	}
+	fmt.Println("Created:", time.Unix(tx.Created, 0).Format(time.RFC3339))
+	fmt.Println("Updated:", time.Unix(tx.Updated, 0).Format(time.RFC3339))
+}
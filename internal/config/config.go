@@ -15,9 +15,10 @@ func init() {
 	C.SetConfigName("config")
 	C.SetConfigType("yaml")
 
-	// current dir or parent dir
+	// current dir or parent dir, repo root for package tests
 	C.AddConfigPath(".")
 	C.AddConfigPath("..")
+	C.AddConfigPath("../..")
 
 	cp := os.Getenv("CONFIG_PATH")
 	if cp != "" {
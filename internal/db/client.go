@@ -44,22 +44,32 @@ func (c *Client) Setup() error {
 
 // init tables
 func (c *Client) tableInitial() error {
-	t := &Project{}
-	exist, err := c.engine.IsTableExist(t)
-	if err != nil {
+	// sync project table, adds columns introduced after creation
+	if err := c.engine.Sync2(&Project{}); err != nil {
+		return err
+	}
+
+	// contracts are versioned per project, the legacy contract table is
+	// migrated into it
+	if err := c.engine.Sync2(&Contract{}); err != nil {
 		return err
 	}
-	if !exist {
-		c.engine.CreateTables(t)
+	if err := c.migrateContracts(); err != nil {
+		return err
 	}
 
-	t2 := &Contract{}
-	exist, err = c.engine.IsTableExist(t2)
-	if err != nil {
+	if err := c.engine.Sync2(&Transaction{}); err != nil {
 		return err
 	}
-	if !exist {
-		c.engine.CreateTables(t2)
+
+	// deployments registered once mined
+	if err := c.engine.Sync2(&PendingDeploy{}); err != nil {
+		return err
+	}
+
+	// chain indexer
+	if err := c.engine.Sync2(&Block{}, &ChainTx{}, &ChainLog{}, &IndexerState{}); err != nil {
+		return err
 	}
 
 	return nil
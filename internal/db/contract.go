@@ -1,35 +1,177 @@
 package db
 
-import "fmt"
+import (
+	"fmt"
 
+	"xorm.io/xorm"
+)
+
+// deployed contract version, a contract is identified by project and name and
+// one of its versions is active; version and created are quoted, unquoted they
+// are xorm's optimistic lock and insert time keywords
 type Contract struct {
-	Name    string `xorm:"name not null pk"`
-	Proj    string `xorm:"proj"`
-	Created int64  `xorm:"created not null"`
+	Id      int64  `xorm:"id pk autoincr"`
+	Proj    string `xorm:"proj not null unique(proj_name_version)"`
+	Name    string `xorm:"name not null unique(proj_name_version)"`
+	Version int    `xorm:"'version' not null unique(proj_name_version)"`
+	Active  bool   `xorm:"active"`
+	Created int64  `xorm:"'created' not null"`
 	Address string `xorm:"address"`
-	ABI     string `xom:"path not null"`
-	BIN     string `xom:"path not null"`
+	ABI     string `xorm:"abi text"`
+	BIN     string `xorm:"bin text"`
+	// set when compiled from source
+	Source   string `xorm:"source text"`
+	Compiler string `xorm:"compiler"`
+	Optimize bool   `xorm:"optimize"`
+	Runs     int    `xorm:"runs"`
+}
+
+func (Contract) TableName() string {
+	return "contracts"
+}
+
+// contract table before versioning, keyed by name only
+type legacyContract struct {
+	Name     string `xorm:"name not null pk"`
+	Proj     string `xorm:"proj"`
+	Created  int64  `xorm:"created not null"`
+	Address  string `xorm:"address"`
+	ABI      string `xorm:"'a_b_i'"`
+	BIN      string `xorm:"'b_i_n'"`
+	Source   string `xorm:"source text"`
+	Compiler string `xorm:"compiler"`
+	Optimize bool   `xorm:"optimize"`
+	Runs     int    `xorm:"runs"`
+}
+
+func (legacyContract) TableName() string {
+	return "contract"
 }
 
+// copy legacy contracts as active first versions, once
+func (c *Client) migrateContracts() error {
+	exist, err := c.engine.IsTableExist(&legacyContract{})
+	if err != nil || !exist {
+		return err
+	}
+	count, err := c.engine.Count(&Contract{})
+	if err != nil || count > 0 {
+		return err
+	}
+	// legacy tables may predate the source columns
+	if err := c.engine.Sync2(&legacyContract{}); err != nil {
+		return err
+	}
+	list := []legacyContract{}
+	if err := c.engine.Find(&list); err != nil {
+		return err
+	}
+	for _, l := range list {
+		contract := &Contract{
+			Proj:     l.Proj,
+			Name:     l.Name,
+			Version:  1,
+			Active:   true,
+			Created:  l.Created,
+			Address:  l.Address,
+			ABI:      l.ABI,
+			BIN:      l.BIN,
+			Source:   l.Source,
+			Compiler: l.Compiler,
+			Optimize: l.Optimize,
+			Runs:     l.Runs,
+		}
+		if _, err := c.engine.InsertOne(contract); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add contract as the next version of proj and name and make it active
 func (c *Client) AddContract(contract *Contract) error {
-	_, err := c.engine.InsertOne(contract)
+	_, err := c.engine.Transaction(func(session *xorm.Session) (interface{}, error) {
+		last := &Contract{}
+		has, err := session.Where("proj = ? and name = ?", contract.Proj, contract.Name).Desc("version").Get(last)
+		if err != nil {
+			return nil, err
+		}
+		contract.Version = 1
+		if has {
+			contract.Version = last.Version + 1
+		}
+		if _, err := session.Where("proj = ? and name = ?", contract.Proj, contract.Name).Cols("active").Update(&Contract{Active: false}); err != nil {
+			return nil, err
+		}
+		contract.Active = true
+		_, err = session.InsertOne(contract)
+		return nil, err
+	})
 	return err
 }
 
-func (c *Client) ListContract() (int64, []Contract, error) {
+// list active contracts, of all projects when proj is empty
+func (c *Client) ListContract(proj string) (int64, []Contract, error) {
 	list := []Contract{}
-	total, err := c.engine.FindAndCount(&list)
+	session := c.engine.Where("active = ?", true)
+	if proj != "" {
+		session = session.And("proj = ?", proj)
+	}
+	total, err := session.Asc("proj", "name").FindAndCount(&list)
 	return total, list, err
 }
 
-func (c *Client) GetContract(name string) (*Contract, error) {
-	contract := &Contract{Name: name}
-	has, err := c.engine.Get(contract)
-	if err != nil {
+// active version of contract name, proj may be empty when the name is unique
+// across projects
+func (c *Client) GetContract(proj, name string) (*Contract, error) {
+	list := []Contract{}
+	session := c.engine.Where("name = ? and active = ?", name, true)
+	if proj != "" {
+		session = session.And("proj = ?", proj)
+	}
+	if err := session.Find(&list); err != nil {
 		return nil, err
 	}
-	if !has {
+	switch len(list) {
+	case 0:
+		if proj != "" {
+			return nil, fmt.Errorf("Contract %s not found in project %s", name, proj)
+		}
 		return nil, fmt.Errorf("Contract %s not found", name)
+	case 1:
+		return &list[0], nil
 	}
-	return contract, nil
+	return nil, fmt.Errorf("Contract %s exists in several projects, project is required", name)
+}
+
+// all versions of contract name in proj, newest first
+func (c *Client) ListContractVersions(proj, name string) ([]Contract, error) {
+	list := []Contract{}
+	err := c.engine.Where("proj = ? and name = ?", proj, name).Desc("version").Find(&list)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("Contract %s not found in project %s", name, proj)
+	}
+	return list, nil
+}
+
+// make version the active one of contract name in proj
+func (c *Client) UseContractVersion(proj, name string, version int) error {
+	_, err := c.engine.Transaction(func(session *xorm.Session) (interface{}, error) {
+		has, err := session.Where("proj = ? and name = ? and version = ?", proj, name, version).Exist(&Contract{})
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			return nil, fmt.Errorf("Contract %s version %d not found in project %s", name, version, proj)
+		}
+		if _, err := session.Where("proj = ? and name = ?", proj, name).Cols("active").Update(&Contract{Active: false}); err != nil {
+			return nil, err
+		}
+		_, err = session.Where("proj = ? and name = ? and version = ?", proj, name, version).Cols("active").Update(&Contract{Active: true})
+		return nil, err
+	})
+	return err
 }
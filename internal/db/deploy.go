@@ -0,0 +1,70 @@
+package db
+
+import "xorm.io/xorm"
+
+// contract of a deployment tx not mined yet, keyed by the sender and nonce
+// of the tx so that a sped up deployment resolves it too
+type PendingDeploy struct {
+	Id       int64  `xorm:"id pk autoincr"`
+	Proj     string `xorm:"proj not null unique(proj_sender_nonce)"`
+	Sender   string `xorm:"sender not null unique(proj_sender_nonce)"`
+	Nonce    uint64 `xorm:"nonce not null unique(proj_sender_nonce)"`
+	Name     string `xorm:"name not null"`
+	Created  int64  `xorm:"'created' not null"`
+	ABI      string `xorm:"abi text"`
+	BIN      string `xorm:"bin text"`
+	Source   string `xorm:"source text"`
+	Compiler string `xorm:"compiler"`
+	Optimize bool   `xorm:"optimize"`
+	Runs     int    `xorm:"runs"`
+}
+
+func (PendingDeploy) TableName() string {
+	return "pending_deploys"
+}
+
+// add pending deployment, replacing a stale one of the same sender and nonce
+func (c *Client) AddPendingDeploy(d *PendingDeploy) error {
+	_, err := c.engine.Transaction(func(session *xorm.Session) (interface{}, error) {
+		if _, err := session.Where("proj = ? and sender = ? and nonce = ?", d.Proj, d.Sender, d.Nonce).Delete(&PendingDeploy{}); err != nil {
+			return nil, err
+		}
+		_, err := session.InsertOne(d)
+		return nil, err
+	})
+	return err
+}
+
+// pending deployment of sender and nonce, nil when there is none
+func (c *Client) GetPendingDeploy(proj, sender string, nonce uint64) (*PendingDeploy, error) {
+	d := &PendingDeploy{}
+	has, err := c.engine.Where("proj = ? and sender = ? and nonce = ?", proj, sender, nonce).Get(d)
+	if err != nil || !has {
+		return nil, err
+	}
+	return d, nil
+}
+
+// register the pending deployment as the next version of its contract at
+// address and remove it; an empty address discards it
+func (c *Client) ResolvePendingDeploy(d *PendingDeploy, address string) error {
+	if address != "" {
+		err := c.AddContract(&Contract{
+			Name:     d.Name,
+			Proj:     d.Proj,
+			Created:  d.Created,
+			Address:  address,
+			ABI:      d.ABI,
+			BIN:      d.BIN,
+			Source:   d.Source,
+			Compiler: d.Compiler,
+			Optimize: d.Optimize,
+			Runs:     d.Runs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := c.engine.ID(d.Id).Delete(&PendingDeploy{})
+	return err
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"fmt"
+
+	"xorm.io/xorm"
+)
+
+// indexed block of a project chain
+type Block struct {
+	Id         int64  `xorm:"id pk autoincr"`
+	Proj       string `xorm:"proj not null unique(proj_number)"`
+	Number     uint64 `xorm:"number not null unique(proj_number)"`
+	Hash       string `xorm:"hash not null index"`
+	ParentHash string `xorm:"parentHash"`
+	Time       uint64 `xorm:"time"`
+	Miner      string `xorm:"miner"`
+	GasUsed    uint64 `xorm:"gasUsed"`
+	GasLimit   uint64 `xorm:"gasLimit"`
+	BaseFee    string `xorm:"baseFee"`
+	TxCount    int    `xorm:"txCount"`
+}
+
+func (Block) TableName() string {
+	return "blocks"
+}
+
+// indexed transaction with its receipt
+type ChainTx struct {
+	Id              int64  `xorm:"id pk autoincr"`
+	Proj            string `xorm:"proj not null unique(proj_hash)"`
+	Hash            string `xorm:"hash not null unique(proj_hash)"`
+	BlockNumber     uint64 `xorm:"blockNumber index"`
+	BlockHash       string `xorm:"blockHash"`
+	TxIndex         uint   `xorm:"txIndex"`
+	Type            uint8  `xorm:"type"`
+	From            string `xorm:"sender index"`
+	To              string `xorm:"recipient index"`
+	Value           string `xorm:"value"`
+	Nonce           uint64 `xorm:"nonce"`
+	Gas             uint64 `xorm:"gas"`
+	GasPrice        string `xorm:"gasPrice"`
+	Input           string `xorm:"input text"`
+	Status          uint64 `xorm:"status"`
+	GasUsed         uint64 `xorm:"gasUsed"`
+	EffectiveGas    string `xorm:"effectiveGasPrice"`
+	ContractAddress string `xorm:"contractAddress"`
+	LogCount        int    `xorm:"logCount"`
+}
+
+func (ChainTx) TableName() string {
+	return "chain_txs"
+}
+
+// indexed log, decoded when emitted by a registered contract
+type ChainLog struct {
+	Id          int64  `xorm:"id pk autoincr"`
+	Proj        string `xorm:"proj not null index(proj_block)"`
+	BlockNumber uint64 `xorm:"blockNumber not null index(proj_block)"`
+	BlockHash   string `xorm:"blockHash"`
+	TxHash      string `xorm:"txHash index"`
+	LogIndex    uint   `xorm:"logIndex"`
+	Address     string `xorm:"address index"`
+	Topics      string `xorm:"topics text"`
+	Data        string `xorm:"data text"`
+	Contract    string `xorm:"contract"`
+	Event       string `xorm:"event"`
+	Args        string `xorm:"args text"`
+}
+
+func (ChainLog) TableName() string {
+	return "chain_logs"
+}
+
+// indexer progress of a project
+type IndexerState struct {
+	Proj    string `xorm:"proj not null pk"`
+	Number  uint64 `xorm:"number"`
+	Hash    string `xorm:"hash"`
+	Updated int64  `xorm:"'updated'"`
+}
+
+func (IndexerState) TableName() string {
+	return "indexer"
+}
+
+// store an indexed block with its txs and logs and advance the indexer
+func (c *Client) SaveBlock(block *Block, txs []ChainTx, logs []ChainLog, updated int64) error {
+	_, err := c.engine.Transaction(func(session *xorm.Session) (interface{}, error) {
+		if _, err := session.InsertOne(block); err != nil {
+			return nil, err
+		}
+		for i := range txs {
+			if _, err := session.InsertOne(&txs[i]); err != nil {
+				return nil, err
+			}
+		}
+		for i := range logs {
+			if _, err := session.InsertOne(&logs[i]); err != nil {
+				return nil, err
+			}
+		}
+		state := &IndexerState{Proj: block.Proj, Number: block.Number, Hash: block.Hash, Updated: updated}
+		return nil, saveIndexerState(session, state)
+	})
+	return err
+}
+
+// remove the indexed block number and everything after it, orphaned by a reorg
+func (c *Client) RollbackBlocks(proj string, number uint64, updated int64) error {
+	_, err := c.engine.Transaction(func(session *xorm.Session) (interface{}, error) {
+		if _, err := session.Where("proj = ? and blockNumber >= ?", proj, number).Delete(&ChainLog{}); err != nil {
+			return nil, err
+		}
+		if _, err := session.Where("proj = ? and blockNumber >= ?", proj, number).Delete(&ChainTx{}); err != nil {
+			return nil, err
+		}
+		if _, err := session.Where("proj = ? and number >= ?", proj, number).Delete(&Block{}); err != nil {
+			return nil, err
+		}
+		last := &Block{}
+		has, err := session.Where("proj = ?", proj).Desc("number").Get(last)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			// nothing left, indexing restarts from its first block
+			_, err := session.Delete(&IndexerState{Proj: proj})
+			return nil, err
+		}
+		state := &IndexerState{Proj: proj, Number: last.Number, Hash: last.Hash, Updated: updated}
+		return nil, saveIndexerState(session, state)
+	})
+	return err
+}
+
+func saveIndexerState(session *xorm.Session, state *IndexerState) error {
+	has, err := session.Exist(&IndexerState{Proj: state.Proj})
+	if err != nil {
+		return err
+	}
+	if has {
+		_, err = session.Where("proj = ?", state.Proj).AllCols().Update(state)
+		return err
+	}
+	_, err = session.InsertOne(state)
+	return err
+}
+
+// indexer progress of proj, nil when the project was never indexed
+func (c *Client) GetIndexerState(proj string) (*IndexerState, error) {
+	state := &IndexerState{Proj: proj}
+	has, err := c.engine.Get(state)
+	if err != nil || !has {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (c *Client) GetBlock(proj string, number uint64) (*Block, error) {
+	block := &Block{}
+	has, err := c.engine.Where("proj = ? and number = ?", proj, number).Get(block)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, fmt.Errorf("Block %d of project %s not indexed", number, proj)
+	}
+	return block, nil
+}
+
+// all contract versions of proj, including inactive ones
+func (c *Client) ListProjectContracts(proj string) ([]Contract, error) {
+	list := []Contract{}
+	err := c.engine.Where("proj = ?", proj).Find(&list)
+	return list, err
+}
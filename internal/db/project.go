@@ -12,6 +12,7 @@ type Project struct {
 	Running   bool   `xorm:"running"`
 	Created   int64  `xorm:"created not null"`
 	NodePort  string `xorm:"nodePort not null"`
+	WsPort    string `xorm:"wsPort"`
 }
 
 func (p *Project) Str2Port() []int32 {
@@ -24,6 +25,18 @@ func (p *Project) Str2Port() []int32 {
 	return portInt32
 }
 
+func (p *Project) Str2WsPort() []int32 {
+	portInt32 := []int32{}
+	if p.WsPort == "" {
+		return portInt32
+	}
+	for _, p := range strings.Split(p.WsPort, ",") {
+		pi, _ := strconv.Atoi(p)
+		portInt32 = append(portInt32, int32(pi))
+	}
+	return portInt32
+}
+
 func (c *Client) AddProject(p *Project) error {
 	_, err := c.engine.InsertOne(p)
 	return err
@@ -0,0 +1,68 @@
+package db
+
+import "fmt"
+
+const (
+	TxPending  = "pending"
+	TxSuccess  = "success"
+	TxFailed   = "failed"
+	TxDropped  = "dropped"
+	TxReplaced = "replaced"
+)
+
+// created and updated are quoted, unquoted they are xorm's insert and update
+// time keywords
+type Transaction struct {
+	Hash            string `xorm:"hash varchar(66) not null pk"`
+	Proj            string `xorm:"proj index"`
+	Contract        string `xorm:"contract"`
+	Method          string `xorm:"method"`
+	Args            string `xorm:"args text"`
+	From            string `xorm:"sender"`
+	Nonce           uint64 `xorm:"nonce"`
+	Raw             string `xorm:"raw text"`
+	Status          string `xorm:"status index"`
+	BlockNumber     uint64 `xorm:"blockNumber"`
+	GasUsed         uint64 `xorm:"gasUsed"`
+	ContractAddress string `xorm:"contractAddress"`
+	RevertReason    string `xorm:"revertReason"`
+	Replaces        string `xorm:"replaces"`
+	Created         int64  `xorm:"'created' not null"`
+	Updated         int64  `xorm:"'updated'"`
+}
+
+func (c *Client) AddTransaction(tx *Transaction) error {
+	_, err := c.engine.InsertOne(tx)
+	return err
+}
+
+func (c *Client) UpdateTransaction(tx *Transaction) error {
+	_, err := c.engine.Where("hash = ?", tx.Hash).AllCols().Update(tx)
+	return err
+}
+
+func (c *Client) GetTransaction(hash string) (*Transaction, error) {
+	tx := &Transaction{Hash: hash}
+	has, err := c.engine.Get(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, fmt.Errorf("Transaction %s not found", hash)
+	}
+	return tx, nil
+}
+
+// list transactions, empty proj or status matches all
+func (c *Client) ListTransaction(proj, status string) (int64, []Transaction, error) {
+	list := []Transaction{}
+	session := c.engine.Desc("created")
+	if proj != "" {
+		session = session.Where("proj = ?", proj)
+	}
+	if status != "" {
+		session = session.And("status = ?", status)
+	}
+	total, err := session.FindAndCount(&list)
+	return total, list, err
+}
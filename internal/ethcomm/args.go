@@ -0,0 +1,336 @@
+package ethcomm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// parse user input into values packable with args. input is either a JSON
+// array, or comma separated values where strings may be double quoted and
+// arrays or tuples are written as JSON, e.g. 0xab..,"a, b",[1,2]
+func ParseArgs(args abi.Arguments, input string) ([]interface{}, error) {
+	input = strings.TrimSpace(input)
+	var values []interface{}
+	var jsonErr error
+	if strings.HasPrefix(input, "[") && decodeJSON(input, &values) == nil && len(values) == len(args) {
+		list, err := ConvertArgs(args, values)
+		if err == nil {
+			return list, nil
+		}
+		// a single array argument may be given without the outer array
+		jsonErr = err
+	}
+
+	pieces, err := splitArgs(input)
+	if err != nil {
+		return nil, err
+	}
+	values = make([]interface{}, len(pieces))
+	for i, p := range pieces {
+		values[i] = p
+	}
+	list, err := ConvertArgs(args, values)
+	if err != nil && jsonErr != nil {
+		return nil, jsonErr
+	}
+	return list, err
+}
+
+// convert decoded JSON values, or raw CLI strings, into values packable with args
+func ConvertArgs(args abi.Arguments, values []interface{}) ([]interface{}, error) {
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("Expected %d arguments %s, got %d", len(args), argsSignature(args), len(values))
+	}
+	list := make([]interface{}, len(args))
+	for i, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		v, err := convertArg(name, arg.Type, values[i])
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func convertArg(name string, t abi.Type, v interface{}) (interface{}, error) {
+	out, err := convertValue(name, t, v)
+	if err != nil {
+		if _, ok := err.(*argError); ok {
+			return nil, err
+		}
+		return nil, &argError{name: name, typ: t.String(), err: err}
+	}
+	return out, nil
+}
+
+func convertValue(name string, t abi.Type, v interface{}) (interface{}, error) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return convertInt(t, v)
+	case abi.BoolTy:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			return strconv.ParseBool(b)
+		}
+	case abi.StringTy:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case json.Number:
+			return s.String(), nil
+		}
+	case abi.AddressTy:
+		if s, ok := v.(string); ok {
+			if !common.IsHexAddress(s) {
+				return nil, fmt.Errorf("not a hex address")
+			}
+			return common.HexToAddress(s), nil
+		}
+	case abi.FixedBytesTy, abi.FunctionTy:
+		if s, ok := v.(string); ok {
+			b, err := bytesArg(s)
+			if err != nil {
+				return nil, err
+			}
+			if len(b) > t.Size {
+				return nil, fmt.Errorf("%d bytes exceed %d", len(b), t.Size)
+			}
+			arr := reflect.New(t.GetType()).Elem()
+			reflect.Copy(arr, reflect.ValueOf(b))
+			return arr.Interface(), nil
+		}
+	case abi.BytesTy:
+		if s, ok := v.(string); ok {
+			return bytesArg(s)
+		}
+	case abi.SliceTy, abi.ArrayTy:
+		list, err := listArg(v)
+		if err != nil {
+			return nil, err
+		}
+		if t.T == abi.ArrayTy && len(list) != t.Size {
+			return nil, fmt.Errorf("expected %d elements, got %d", t.Size, len(list))
+		}
+		var out reflect.Value
+		if t.T == abi.SliceTy {
+			out = reflect.MakeSlice(t.GetType(), len(list), len(list))
+		} else {
+			out = reflect.New(t.GetType()).Elem()
+		}
+		for i, item := range list {
+			e, err := convertArg(fmt.Sprintf("%s[%d]", name, i), *t.Elem, item)
+			if err != nil {
+				return nil, err
+			}
+			out.Index(i).Set(reflect.ValueOf(e))
+		}
+		return out.Interface(), nil
+	case abi.TupleTy:
+		fields, err := tupleArg(t, v)
+		if err != nil {
+			return nil, err
+		}
+		out := reflect.New(t.TupleType).Elem()
+		for i, elem := range t.TupleElems {
+			e, err := convertArg(name+"."+t.TupleRawNames[i], *elem, fields[i])
+			if err != nil {
+				return nil, err
+			}
+			out.Field(i).Set(reflect.ValueOf(e))
+		}
+		return out.Interface(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type")
+	}
+	return nil, fmt.Errorf("unexpected value %v", v)
+}
+
+// integers of up to 64 bits are packed from native go types, larger ones from *big.Int
+func convertInt(t abi.Type, v interface{}) (interface{}, error) {
+	var s string
+	switch n := v.(type) {
+	case json.Number:
+		s = n.String()
+	case string:
+		s = strings.TrimSpace(n)
+	default:
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+	i, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("not an integer: %q", s)
+	}
+
+	if t.T == abi.UintTy {
+		if i.Sign() < 0 || i.BitLen() > t.Size {
+			return nil, fmt.Errorf("%s out of range", s)
+		}
+	} else {
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if i.Cmp(limit) >= 0 || i.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%s out of range", s)
+		}
+	}
+
+	typ := t.GetType()
+	switch typ.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(i.Uint64()).Convert(typ).Interface(), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(i.Int64()).Convert(typ).Interface(), nil
+	}
+	return i, nil
+}
+
+// bytes from 0x prefixed hex, other strings are taken as text, including 0x
+// prefixed ones with non hex characters such as 0xkey
+func bytesArg(s string) ([]byte, error) {
+	if (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")) && isHexDigits(s[2:]) {
+		return hexutil.Decode(s)
+	}
+	return []byte(s), nil
+}
+
+func isHexDigits(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// list of an array argument, given as JSON text on the command line
+func listArg(v interface{}) ([]interface{}, error) {
+	if s, ok := v.(string); ok {
+		var list []interface{}
+		if err := decodeJSON(s, &list); err != nil {
+			return nil, fmt.Errorf("expected a JSON array: %w", err)
+		}
+		return list, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array")
+	}
+	return list, nil
+}
+
+// tuple fields in order, given as a JSON array or an object keyed by field names
+func tupleArg(t abi.Type, v interface{}) ([]interface{}, error) {
+	if s, ok := v.(string); ok {
+		if err := decodeJSON(s, &v); err != nil {
+			return nil, fmt.Errorf("expected a JSON object or array: %w", err)
+		}
+	}
+	switch fields := v.(type) {
+	case []interface{}:
+		if len(fields) != len(t.TupleElems) {
+			return nil, fmt.Errorf("expected %d fields, got %d", len(t.TupleElems), len(fields))
+		}
+		return fields, nil
+	case map[string]interface{}:
+		list := make([]interface{}, len(t.TupleElems))
+		for i, name := range t.TupleRawNames {
+			f, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("missing field %s", name)
+			}
+			list[i] = f
+		}
+		if len(fields) != len(list) {
+			return nil, fmt.Errorf("unknown fields, expected %s", strings.Join(t.TupleRawNames, ", "))
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expected an object or array")
+}
+
+// split comma separated CLI input, commas inside double quotes or brackets
+// do not separate values; quoted values are unquoted
+func splitArgs(input string) ([]string, error) {
+	if input == "" {
+		return nil, nil
+	}
+	pieces := []string{}
+	depth, quoted, escaped, start := 0, false, false, 0
+	for i, r := range input {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			pieces = append(pieces, input[start:i])
+			start = i + 1
+		}
+	}
+	if quoted || depth != 0 {
+		return nil, fmt.Errorf("Unbalanced quotes or brackets in input: %s", input)
+	}
+	pieces = append(pieces, input[start:])
+
+	for i, p := range pieces {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, `"`) {
+			var s string
+			if err := json.Unmarshal([]byte(p), &s); err != nil {
+				return nil, fmt.Errorf("Invalid quoted value %s: %w", p, err)
+			}
+			p = s
+		}
+		pieces[i] = p
+	}
+	return pieces, nil
+}
+
+func decodeJSON(s string, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func argsSignature(args abi.Arguments) string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = strings.TrimSpace(arg.Type.String() + " " + arg.Name)
+	}
+	return "(" + strings.Join(types, ", ") + ")"
+}
+
+// invalid argument, named by its path within the method inputs
+type argError struct {
+	name string
+	typ  string
+	err  error
+}
+
+func (e *argError) Error() string {
+	return fmt.Sprintf("Invalid argument %s (%s): %s", e.name, e.typ, e.err)
+}
+
+func (e *argError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,141 @@
+package ethcomm
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func testArgs(t *testing.T, types ...string) abi.Arguments {
+	t.Helper()
+	args := abi.Arguments{}
+	for _, typ := range types {
+		at, err := abi.NewType(typ, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, abi.Argument{Type: at})
+	}
+	return args
+}
+
+func testTupleArgs(t *testing.T) abi.Arguments {
+	t.Helper()
+	at, err := abi.NewType("tuple", "", []abi.ArgumentMarshaling{
+		{Name: "id", Type: "uint256"},
+		{Name: "owner", Type: "address"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abi.Arguments{{Name: "order", Type: at}}
+}
+
+func bytes32(s string) [32]byte {
+	var b [32]byte
+	copy(b[:], s)
+	return b
+}
+
+func TestParseArgs(t *testing.T) {
+	addr := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	big256, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+	tests := []struct {
+		name  string
+		types []string
+		input string
+		want  []interface{}
+	}{
+		{"uint8", []string{"uint8"}, "255", []interface{}{uint8(255)}},
+		{"int64 negative", []string{"int64"}, "-5", []interface{}{int64(-5)}},
+		{"uint64 hex", []string{"uint64"}, "0x10", []interface{}{uint64(16)}},
+		{"uint256 max", []string{"uint256"}, big256.String(), []interface{}{big256}},
+		{"int256 json", []string{"int256"}, "[-1]", []interface{}{big.NewInt(-1)}},
+		{"address", []string{"address"}, addr.Hex(), []interface{}{addr}},
+		{"address and amount", []string{"address", "uint256"}, addr.Hex() + ",1000", []interface{}{addr, big.NewInt(1000)}},
+		{"bool and string", []string{"bool", "string"}, `true,"a, b"`, []interface{}{true, "a, b"}},
+		{"bytes hex", []string{"bytes"}, "0x0102", []interface{}{[]byte{1, 2}}},
+		{"bytes text", []string{"bytes"}, "abc", []interface{}{[]byte("abc")}},
+		{"bytes32 text", []string{"bytes32"}, "key1", []interface{}{bytes32("key1")}},
+		{"bytes32 hex", []string{"bytes32"}, "0x6b657931", []interface{}{bytes32("key1")}},
+		{"bytes32 text with 0x prefix", []string{"bytes32"}, "0xkey", []interface{}{bytes32("0xkey")}},
+		{"bytes4", []string{"bytes4"}, "0xa9059cbb", []interface{}{[4]byte{0xa9, 0x05, 0x9c, 0xbb}}},
+		{"slice", []string{"uint256[]"}, "[1,2]", []interface{}{[]*big.Int{big.NewInt(1), big.NewInt(2)}}},
+		{"slice of json array", []string{"uint8[]"}, "[[1,2]]", []interface{}{[]uint8{1, 2}}},
+		{"fixed array", []string{"address[2]"}, `["` + addr.Hex() + `","` + addr.Hex() + `"]`, []interface{}{[2]common.Address{addr, addr}}},
+		{"array and string", []string{"uint8[]", "string"}, `[1,2],x`, []interface{}{[]uint8{1, 2}, "x"}},
+		{"empty", nil, "", []interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArgs(testArgs(t, tt.types...), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgsTuple(t *testing.T) {
+	addr := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	inputs := []string{
+		`{"id": 7, "owner": "` + addr.Hex() + `"}`,
+		`[7, "` + addr.Hex() + `"]`,
+		`[{"id": "0x7", "owner": "` + addr.Hex() + `"}]`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			got, err := ParseArgs(testTupleArgs(t), input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := reflect.ValueOf(got[0])
+			if id := v.Field(0).Interface().(*big.Int); id.Int64() != 7 {
+				t.Errorf("id %s, want 7", id)
+			}
+			if owner := v.Field(1).Interface().(common.Address); owner != addr {
+				t.Errorf("owner %s, want %s", owner.Hex(), addr.Hex())
+			}
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []string
+		input string
+		err   string
+	}{
+		{"uint8 overflow", []string{"uint8"}, "256", "out of range"},
+		{"uint negative", []string{"uint256"}, "-1", "out of range"},
+		{"int8 underflow", []string{"int8"}, "-129", "out of range"},
+		{"not an integer", []string{"uint256"}, "1.5", "not an integer"},
+		{"bad address", []string{"address"}, "0x1234", "not a hex address"},
+		{"bytes32 too long", []string{"bytes32"}, strings.Repeat("a", 33), "33 bytes exceed 32"},
+		{"odd hex", []string{"bytes"}, "0xabc", "Invalid argument"},
+		{"fixed array size", []string{"uint8[2]"}, "[1,2,3]", "expected 2 elements"},
+		{"tuple field", nil, `{"id": 1}`, "missing field owner"},
+		{"count", []string{"uint8", "uint8"}, "1", "Expected 2 arguments"},
+		{"unbalanced", []string{"string"}, `"a`, "Unbalanced"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := testArgs(t, tt.types...)
+			if tt.types == nil {
+				args = testTupleArgs(t)
+			}
+			_, err := ParseArgs(args, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
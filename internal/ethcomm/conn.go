@@ -1,8 +1,11 @@
 package ethcomm
 
 import (
+	"context"
 	"ethbaas/internal/config"
 	"fmt"
+	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/ethclient/gethclient"
@@ -13,14 +16,43 @@ type ChainConn struct {
 	RpcCli  *rpc.Client
 	GethCli *gethclient.Client
 	EthCli  *ethclient.Client
+
+	mu      sync.Mutex
+	chainId *big.Int
 }
 
 func (c *ChainConn) Close() {
 	c.RpcCli.Close()
 }
+
+// chain id of the node, cached after first query
+func (c *ChainConn) ChainID(ctx context.Context) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.chainId == nil {
+		chainId, err := c.EthCli.ChainID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.chainId = chainId
+	}
+	return new(big.Int).Set(c.chainId), nil
+}
+
+// dial node http rpc
 func NewConn(port int32) (*ChainConn, error) {
 	url := fmt.Sprintf("%s:%d", config.C.GetString("ethurl"), port)
-	rpcCli, err := rpc.Dial(url)
+	return dialContext(context.Background(), url)
+}
+
+// dial node websocket rpc, required by subscriptions
+func NewWSConn(port int32) (*ChainConn, error) {
+	url := fmt.Sprintf("%s:%d", config.C.GetString("ethwsurl"), port)
+	return dialContext(context.Background(), url)
+}
+
+func dialContext(ctx context.Context, url string) (*ChainConn, error) {
+	rpcCli, err := rpc.DialContext(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,122 @@
+package ethcomm
+
+import (
+	"ethbaas/internal/model"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// topics matching event with indexed args equal to filters, given as
+// name=value; values of the same arg are alternatives
+func EventTopics(event abi.Event, filters []string) ([][]common.Hash, error) {
+	indexed := abi.Arguments{}
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+
+	query := make([][]interface{}, len(indexed))
+	for _, f := range filters {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("Invalid filter %q, expected name=value", f)
+		}
+		name, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		i := argIndex(indexed, name)
+		if i < 0 {
+			return nil, fmt.Errorf("Event %s has no indexed argument %s", event.Name, name)
+		}
+		v, err := convertArg(name, indexed[i].Type, value)
+		if err != nil {
+			return nil, err
+		}
+		query[i] = append(query[i], v)
+	}
+
+	topics, err := abi.MakeTopics(query...)
+	if err != nil {
+		return nil, err
+	}
+	// trailing wildcards are implied
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+	return append([][]common.Hash{{event.ID}}, topics...), nil
+}
+
+func argIndex(args abi.Arguments, name string) int {
+	for i, arg := range args {
+		if arg.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// decode log with the events of contractAbi, ok is false for logs of events
+// not in the abi
+func DecodeLog(contractAbi *abi.ABI, log types.Log, bytesAsString bool) (*model.Event, bool, error) {
+	if len(log.Topics) == 0 {
+		return nil, false, nil
+	}
+	event, err := contractAbi.EventByID(log.Topics[0])
+	if err != nil {
+		return nil, false, nil
+	}
+
+	values, err := event.Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, true, fmt.Errorf("Decoding %s log %d of tx %s: %w", event.Name, log.Index, log.TxHash.Hex(), err)
+	}
+
+	fields := make(model.Fields, len(event.Inputs))
+	topic, data := 1, 0
+	for i, arg := range event.Inputs {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		field := model.Field{Name: name, Type: arg.Type.String()}
+		if !arg.Indexed {
+			field.Value = FormatValues(abi.Arguments{arg}, values[data:data+1], bytesAsString)[0].Value
+			data++
+		} else if topic < len(log.Topics) {
+			field.Value, err = topicValue(arg, log.Topics[topic], bytesAsString)
+			if err != nil {
+				return nil, true, fmt.Errorf("Decoding %s log %d of tx %s: %w", event.Name, log.Index, log.TxHash.Hex(), err)
+			}
+			topic++
+		}
+		fields[i] = field
+	}
+
+	return &model.Event{
+		Event:       event.Name,
+		Address:     log.Address.Hex(),
+		BlockNumber: log.BlockNumber,
+		BlockHash:   log.BlockHash.Hex(),
+		TxHash:      log.TxHash.Hex(),
+		TxIndex:     log.TxIndex,
+		LogIndex:    log.Index,
+		Removed:     log.Removed,
+		Args:        fields,
+	}, true, nil
+}
+
+// value of an indexed arg, dynamic types are only known by their hash
+func topicValue(arg abi.Argument, topic common.Hash, bytesAsString bool) (interface{}, error) {
+	switch arg.Type.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return topic.Hex(), nil
+	}
+	m := map[string]interface{}{}
+	if err := abi.ParseTopicsIntoMap(m, abi.Arguments{arg}, []common.Hash{topic}); err != nil {
+		return nil, err
+	}
+	return FormatValues(abi.Arguments{arg}, []interface{}{m[arg.Name]}, bytesAsString)[0].Value, nil
+}
@@ -0,0 +1,80 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/config"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// set the gas limit of auth, gasLimit overrides estimation when non zero;
+// to is nil for contract creation
+func SetGasLimit(ctx context.Context, conn *ChainConn, auth *bind.TransactOpts, gasLimit uint64, to *common.Address, data []byte) error {
+	if gasLimit > 0 {
+		auth.GasLimit = gasLimit
+		return nil
+	}
+	gas, err := EstimateGas(ctx, conn, auth, to, data)
+	if err != nil {
+		return err
+	}
+	auth.GasLimit = gas
+	return nil
+}
+
+// estimated gas with the configured safety margin
+func EstimateGas(ctx context.Context, conn *ChainConn, auth *bind.TransactOpts, to *common.Address, data []byte) (uint64, error) {
+	msg := ethereum.CallMsg{
+		From:      auth.From,
+		To:        to,
+		GasPrice:  auth.GasPrice,
+		GasFeeCap: auth.GasFeeCap,
+		GasTipCap: auth.GasTipCap,
+		Value:     auth.Value,
+		Data:      data,
+	}
+	gas, err := conn.EthCli.EstimateGas(ctx, msg)
+	if err != nil {
+		if reason, ok := RevertReason(err); ok {
+			return 0, fmt.Errorf("Gas estimation failed, tx reverts: %s", reason)
+		}
+		return 0, fmt.Errorf("Gas estimation failed: %w", err)
+	}
+
+	config.C.SetDefault("gas.margin", 1.2)
+	margin := config.C.GetFloat64("gas.margin")
+	if margin < 1 {
+		margin = 1
+	}
+	return uint64(math.Ceil(float64(gas) * margin)), nil
+}
+
+// revert reason carried by an rpc error of a reverted call
+func RevertReason(err error) (string, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return "", false
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return "", false
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return "", false
+	}
+	reason, unpackErr := abi.UnpackRevert(data)
+	if unpackErr != nil {
+		// custom error or panic, show raw data
+		return hexData, true
+	}
+	return reason, true
+}
@@ -4,46 +4,133 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"ethbaas/internal/config"
-	"fmt"
+	"ethbaas/internal/model"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// Deprecated: plaintext key in config, use keystore accounts instead.
 func GetAdminPk() (*ecdsa.PrivateKey, error) {
 	adminPk := config.C.GetString("adminpk")
 
 	return crypto.HexToECDSA(adminPk)
 }
 
-func GenTxOpts(conn *ChainConn) (*bind.TransactOpts, error) {
-	privateKey, err := GetAdminPk()
+// generate transact opts signed by signer, nonce is left to the nonce manager;
+// dynamic fee txs are used when the chain is london enabled unless legacy
+func GenTxOpts(ctx context.Context, conn *ChainConn, signer Signer, opts model.TxOptions) (*bind.TransactOpts, error) {
+	chainId, err := conn.ChainID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+	auth := NewTransactor(ctx, signer, chainId)
+	auth.Value = big.NewInt(0)
+	if opts.Value != nil {
+		auth.Value = new(big.Int).Set(opts.Value)
 	}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	nonce, err := conn.EthCli.PendingNonceAt(context.Background(), fromAddress)
+	head, err := conn.EthCli.HeaderByNumber(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Legacy || head.BaseFee == nil {
+		gasPrice, err := conn.EthCli.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		auth.GasPrice = gasPrice
+		return auth, nil
+	}
 
-	gasPrice, err := conn.EthCli.SuggestGasPrice(context.Background())
+	tip, err := conn.EthCli.SuggestGasTipCap(ctx)
 	if err != nil {
 		return nil, err
 	}
+	auth.GasTipCap = tip
+	auth.GasFeeCap = maxFee(head.BaseFee, tip)
+	return auth, nil
+}
 
-	auth := bind.NewKeyedTransactor(privateKey)
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)
-	auth.GasLimit = uint64(300000)
-	auth.GasPrice = gasPrice
+// fee cap leaving room for base fee growth: baseFee * multiplier + tip
+func maxFee(baseFee, tip *big.Int) *big.Int {
+	config.C.SetDefault("gas.maxfeemultiplier", 2)
+	multiplier := config.C.GetFloat64("gas.maxfeemultiplier")
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	fee, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(multiplier)).Int(nil)
+	return fee.Add(fee, tip)
+}
+
+// build an unsigned tx with the fees, gas and value of auth
+func NewTx(auth *bind.TransactOpts, chainId *big.Int, nonce uint64, to *common.Address, data []byte) *types.Transaction {
+	value := auth.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if auth.GasFeeCap != nil {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainId,
+			Nonce:     nonce,
+			GasTipCap: auth.GasTipCap,
+			GasFeeCap: auth.GasFeeCap,
+			Gas:       auth.GasLimit,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: auth.GasPrice,
+		Gas:      auth.GasLimit,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	})
+}
+
+// transact opts replacing tx, fees are bumped by at least gas.bumppercent
+// over those of tx and never below the current suggestion
+func GenReplaceOpts(ctx context.Context, conn *ChainConn, signer Signer, tx *types.Transaction) (*bind.TransactOpts, error) {
+	opts := model.TxOptions{Legacy: tx.Type() == types.LegacyTxType}
+	auth, err := GenTxOpts(ctx, conn, signer, opts)
+	if err != nil {
+		return nil, err
+	}
+	if auth.GasFeeCap == nil {
+		auth.GasPrice = maxBig(auth.GasPrice, bump(tx.GasPrice()))
+		return auth, nil
+	}
+	auth.GasTipCap = maxBig(auth.GasTipCap, bump(tx.GasTipCap()))
+	auth.GasFeeCap = maxBig(auth.GasFeeCap, bump(tx.GasFeeCap()))
 	return auth, nil
 }
+
+// fee raised by gas.bumppercent, nodes reject replacements bumped less than 10%
+func bump(fee *big.Int) *big.Int {
+	config.C.SetDefault("gas.bumppercent", 10)
+	percent := config.C.GetInt64("gas.bumppercent")
+	if percent < 10 {
+		percent = 10
+	}
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+percent))
+	bumped.Div(bumped, big.NewInt(100))
+	// tiny fees round down to themselves
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
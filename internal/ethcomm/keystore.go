@@ -0,0 +1,72 @@
+package ethcomm
+
+import (
+	"ethbaas/internal/config"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/term"
+)
+
+const (
+	PassphraseEnv    = "ETHBAAS_PASSPHRASE"
+	NewPassphraseEnv = "ETHBAAS_NEW_PASSPHRASE"
+)
+
+// encrypted keystore in the configured directory
+func OpenKeystore() *keystore.KeyStore {
+	config.C.SetDefault("keystore", ".projects/keystore")
+	dir := config.C.GetString("keystore")
+	return keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
+// find keystore account by address
+func FindAccount(ks *keystore.KeyStore, address string) (accounts.Account, error) {
+	if !common.IsHexAddress(address) {
+		return accounts.Account{}, fmt.Errorf("Invalid account address: %s", address)
+	}
+	acc, err := ks.Find(accounts.Account{Address: common.HexToAddress(address)})
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("Account %s not in keystore: %w", address, err)
+	}
+	return acc, nil
+}
+
+// passphrase of keystore accounts, read from env or prompted on terminal
+func Passphrase(prompt string) (string, error) {
+	return readPassphrase(PassphraseEnv, prompt)
+}
+
+// passphrase of keystore accounts from env only, where no one can be prompted
+func EnvPassphrase() (string, error) {
+	if pass, ok := os.LookupEnv(PassphraseEnv); ok {
+		return pass, nil
+	}
+	return "", fmt.Errorf("Passphrase required, set %s", PassphraseEnv)
+}
+
+// passphrase protecting new or exported keys
+func NewPassphrase(prompt string) (string, error) {
+	return readPassphrase(NewPassphraseEnv, prompt)
+}
+
+func readPassphrase(env, prompt string) (string, error) {
+	if pass, ok := os.LookupEnv(env); ok {
+		return pass, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("No terminal to prompt passphrase, set %s", env)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,221 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/config"
+	"ethbaas/internal/log"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type accountNonce struct {
+	mu       sync.Mutex
+	synced   bool
+	checked  time.Time
+	next     uint64
+	released []uint64
+	inflight map[uint64]bool
+	// pending nonce suspected as gap on last check
+	gap *uint64
+}
+
+// NonceManager hands out nonces per project and account locally, so
+// transactions of one account can be submitted concurrently. Projects share
+// chain id and accounts, so state is not keyed by chain id.
+type NonceManager struct {
+	mu            sync.Mutex
+	accounts      map[string]*accountNonce
+	checkInterval time.Duration
+}
+
+var DefaultNonceManager = NewNonceManager()
+
+func NewNonceManager() *NonceManager {
+	config.C.SetDefault("nonce.checkinterval", "10s")
+	m := &NonceManager{
+		accounts:      map[string]*accountNonce{},
+		checkInterval: config.C.GetDuration("nonce.checkinterval"),
+	}
+	return m
+}
+
+// send with a managed nonce, resync and retry once on nonce conflicts
+func (m *NonceManager) Send(ctx context.Context, proj string, conn *ChainConn, from common.Address, send func(nonce uint64) error) error {
+	for attempt := 0; ; attempt++ {
+		nonce, err := m.Acquire(ctx, conn, proj, from)
+		if err != nil {
+			return err
+		}
+		err = send(nonce)
+		m.Done(proj, from, nonce, err)
+		if !IsNonceError(err) || attempt > 0 {
+			return err
+		}
+		log.Logger.Warnf("Nonce %d of %s rejected, resyncing: %s", nonce, from.Hex(), err)
+	}
+}
+
+// next nonce of account
+func (m *NonceManager) Acquire(ctx context.Context, conn *ChainConn, proj string, from common.Address) (uint64, error) {
+	an := m.account(proj, from)
+	an.mu.Lock()
+	defer an.mu.Unlock()
+
+	if !an.synced || time.Since(an.checked) > m.checkInterval {
+		pending, err := conn.EthCli.PendingNonceAt(ctx, from)
+		if err != nil {
+			return 0, err
+		}
+		an.sync(from, pending)
+	}
+
+	var nonce uint64
+	if len(an.released) > 0 {
+		nonce = an.released[0]
+		an.released = an.released[1:]
+	} else {
+		nonce = an.next
+		an.next++
+	}
+	an.inflight[nonce] = true
+	return nonce, nil
+}
+
+// report the send result of an acquired nonce
+func (m *NonceManager) Done(proj string, from common.Address, nonce uint64, err error) {
+	an := m.account(proj, from)
+	an.mu.Lock()
+	defer an.mu.Unlock()
+
+	delete(an.inflight, nonce)
+	switch {
+	case err == nil:
+	case IsNonceError(err), IsKnownTxError(err):
+		an.synced = false
+	case IsRejected(err):
+		// tx never reached the pool, hand the nonce out again to avoid a gap
+		an.released = append(an.released, nonce)
+		sort.Slice(an.released, func(i, j int) bool { return an.released[i] < an.released[j] })
+	default:
+		// the tx may be in the pool, e.g. on a timeout after sending; keep
+		// the nonce used, a gap left behind is reset by the next checks
+	}
+}
+
+// forget local state of account, next acquire resyncs from the node
+func (m *NonceManager) Reset(proj string, from common.Address) {
+	an := m.account(proj, from)
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	an.synced = false
+}
+
+func (m *NonceManager) account(proj string, from common.Address) *accountNonce {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%s/%s", proj, from.Hex())
+	an, ok := m.accounts[key]
+	if !ok {
+		an = &accountNonce{inflight: map[uint64]bool{}}
+		m.accounts[key] = an
+	}
+	return an
+}
+
+// align local state with the pending nonce reported by the node
+func (an *accountNonce) sync(from common.Address, pending uint64) {
+	defer func() {
+		an.synced = true
+		an.checked = time.Now()
+	}()
+
+	if !an.synced || pending > an.next {
+		// first use or txs sent elsewhere
+		an.next = pending
+		released := []uint64{}
+		for _, n := range an.released {
+			if n >= pending {
+				released = append(released, n)
+			}
+		}
+		an.released = released
+		return
+	}
+
+	// nonce pending on node is neither in flight nor queued for reuse,
+	// txs after it were dropped and will never be mined; a gap must be seen
+	// twice in a row since the tx may not have reached this node yet
+	if pending < an.next && !an.inflight[pending] && !an.isReleased(pending) {
+		if an.gap != nil && *an.gap == pending {
+			log.Logger.Warnf("Nonce gap of %s at %d, local next %d, resetting", from.Hex(), pending, an.next)
+			an.next = pending
+			an.released = []uint64{}
+			an.gap = nil
+			return
+		}
+		an.gap = &pending
+		return
+	}
+	an.gap = nil
+}
+
+func (an *accountNonce) isReleased(nonce uint64) bool {
+	for _, n := range an.released {
+		if n == nonce {
+			return true
+		}
+	}
+	return false
+}
+
+// node rejected the tx because its nonce was used
+func IsNonceError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+// errors the tx failed with before reaching the node
+var ErrNotSent = errors.New("Tx not sent")
+
+// mark err as raised before the tx was submitted, e.g. by signing
+func NotSent(err error) error {
+	if err == nil || errors.Is(err, ErrNotSent) {
+		return err
+	}
+	return &notSentError{err: err}
+}
+
+// error before submission, matches ErrNotSent and unwraps to its cause
+type notSentError struct {
+	err error
+}
+
+func (e *notSentError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrNotSent, e.err)
+}
+
+func (e *notSentError) Is(target error) bool {
+	return target == ErrNotSent
+}
+
+func (e *notSentError) Unwrap() error {
+	return e.err
+}
+
+// the tx definitely did not enter the pool: the node answered with an error,
+// or it failed before submission
+func IsRejected(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) || errors.Is(err, ErrNotSent)
+}
+
+// node already has the identical tx in its pool
+func IsKnownTxError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "already known")
+}
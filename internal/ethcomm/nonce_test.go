@@ -0,0 +1,170 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type testRPCError struct{}
+
+func (testRPCError) Error() string  { return "insufficient funds for gas * price + value" }
+func (testRPCError) ErrorCode() int { return -32000 }
+
+var testFrom = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+// manager with proj/from synced at next, acquire needs no node then
+func syncedManager(proj string, next uint64) *NonceManager {
+	m := &NonceManager{accounts: map[string]*accountNonce{}, checkInterval: time.Hour}
+	an := m.account(proj, testFrom)
+	an.synced, an.checked, an.next = true, time.Now(), next
+	return m
+}
+
+func acquire(t *testing.T, m *NonceManager, proj string) uint64 {
+	t.Helper()
+	nonce, err := m.Acquire(context.Background(), nil, proj, testFrom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nonce
+}
+
+func TestNonceDone(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		next uint64
+	}{
+		{"sent", nil, 6},
+		{"rejected by node", testRPCError{}, 5},
+		{"not sent", NotSent(errors.New("signer locked")), 5},
+		{"timeout after send", context.DeadlineExceeded, 6},
+		{"unknown error", errors.New("connection reset"), 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := syncedManager("a", 5)
+			nonce := acquire(t, m, "a")
+			if nonce != 5 {
+				t.Fatalf("got nonce %d, want 5", nonce)
+			}
+			m.Done("a", testFrom, nonce, tt.err)
+			if got := acquire(t, m, "a"); got != tt.next {
+				t.Errorf("next nonce %d, want %d", got, tt.next)
+			}
+		})
+	}
+}
+
+func TestNonceNonceErrorResyncs(t *testing.T) {
+	m := syncedManager("a", 5)
+	nonce := acquire(t, m, "a")
+	m.Done("a", testFrom, nonce, errors.New("nonce too low"))
+	if m.account("a", testFrom).synced {
+		t.Error("account still synced after nonce error")
+	}
+}
+
+func TestNoncePerProject(t *testing.T) {
+	m := syncedManager("a", 7)
+	b := m.account("b", testFrom)
+	b.synced, b.checked, b.next = true, time.Now(), 0
+
+	if got := acquire(t, m, "a"); got != 7 {
+		t.Errorf("project a nonce %d, want 7", got)
+	}
+	if got := acquire(t, m, "b"); got != 0 {
+		t.Errorf("project b nonce %d, want 0", got)
+	}
+}
+
+func TestNonceReleasedReusedInOrder(t *testing.T) {
+	m := syncedManager("a", 5)
+	n5, n6, n7 := acquire(t, m, "a"), acquire(t, m, "a"), acquire(t, m, "a")
+	m.Done("a", testFrom, n7, testRPCError{})
+	m.Done("a", testFrom, n5, testRPCError{})
+	m.Done("a", testFrom, n6, nil)
+	for _, want := range []uint64{5, 7, 8} {
+		if got := acquire(t, m, "a"); got != want {
+			t.Errorf("nonce %d, want %d", got, want)
+		}
+	}
+}
+
+func TestNonceSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		next     uint64
+		inflight []uint64
+		released []uint64
+		// pending nonces reported by consecutive checks
+		pending []uint64
+		want    uint64
+	}{
+		{"in step", 5, nil, nil, []uint64{5}, 5},
+		{"sent elsewhere", 5, nil, nil, []uint64{8}, 8},
+		{"gap seen once", 8, nil, nil, []uint64{5}, 8},
+		{"gap seen twice resets", 8, nil, nil, []uint64{5, 5}, 5},
+		{"gap moved is not reset", 8, nil, nil, []uint64{5, 6}, 8},
+		{"in flight is no gap", 8, []uint64{5}, nil, []uint64{5, 5}, 8},
+		{"released is no gap", 8, nil, []uint64{5}, []uint64{5, 5}, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			an := &accountNonce{synced: true, next: tt.next, released: tt.released, inflight: map[uint64]bool{}}
+			for _, n := range tt.inflight {
+				an.inflight[n] = true
+			}
+			for _, p := range tt.pending {
+				an.sync(testFrom, p)
+			}
+			if an.next != tt.want {
+				t.Errorf("next %d, want %d", an.next, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonceSyncFirstUse(t *testing.T) {
+	an := &accountNonce{next: 9, released: []uint64{3, 10}, inflight: map[uint64]bool{}}
+	an.sync(testFrom, 4)
+	if an.next != 4 || len(an.released) != 1 || an.released[0] != 10 {
+		t.Errorf("got next %d released %v, want 4 [10]", an.next, an.released)
+	}
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Address() common.Address { return testFrom }
+
+func (failingSigner) SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	return nil, errors.New("account locked")
+}
+
+func TestNonceSignerFailureReleased(t *testing.T) {
+	m := syncedManager("a", 5)
+	opts := NewTransactor(context.Background(), failingSigner{}, big.NewInt(1874))
+	err := m.Send(context.Background(), "a", nil, testFrom, func(nonce uint64) error {
+		_, err := opts.Signer(testFrom, types.NewTx(&types.LegacyTx{Nonce: nonce}))
+		return err
+	})
+	if !errors.Is(err, ErrNotSent) {
+		t.Fatalf("got error %v, want not sent", err)
+	}
+	if got := acquire(t, m, "a"); got != 5 {
+		t.Errorf("next nonce %d, want 5", got)
+	}
+
+	// signing for another account is not sent either
+	_, err = opts.Signer(common.HexToAddress("0x01"), types.NewTx(&types.LegacyTx{}))
+	if !errors.Is(err, ErrNotSent) || !errors.Is(err, bind.ErrNotAuthorized) {
+		t.Errorf("got error %v, want not sent and not authorized", err)
+	}
+}
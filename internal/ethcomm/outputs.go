@@ -0,0 +1,79 @@
+package ethcomm
+
+import (
+	"bytes"
+	"ethbaas/internal/model"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// unpack call output into named fields; big ints are rendered as decimal
+// strings, addresses and bytes as hex, or fixed bytes as trimmed text when
+// bytesAsString is set
+func DecodeOutputs(args abi.Arguments, data []byte, bytesAsString bool) (model.Fields, error) {
+	values, err := args.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	return FormatValues(args, values, bytesAsString), nil
+}
+
+// render unpacked values of args
+func FormatValues(args abi.Arguments, values []interface{}, bytesAsString bool) model.Fields {
+	fields := make(model.Fields, len(values))
+	for i, v := range values {
+		name := args[i].Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		fields[i] = model.Field{
+			Name:  name,
+			Type:  args[i].Type.String(),
+			Value: formatValue(args[i].Type, reflect.ValueOf(v), bytesAsString),
+		}
+	}
+	return fields
+}
+
+func formatValue(t abi.Type, v reflect.Value, bytesAsString bool) interface{} {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if b, ok := v.Interface().(*big.Int); ok {
+			return b.String()
+		}
+		return v.Interface()
+	case abi.AddressTy:
+		return v.Interface().(common.Address).Hex()
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		if bytesAsString {
+			return string(bytes.TrimRight(b, "\x00"))
+		}
+		return hexutil.Encode(b)
+	case abi.BytesTy:
+		return hexutil.Encode(v.Bytes())
+	case abi.SliceTy, abi.ArrayTy:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = formatValue(*t.Elem, v.Index(i), bytesAsString)
+		}
+		return list
+	case abi.TupleTy:
+		fields := make(model.Fields, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			fields[i] = model.Field{
+				Name:  t.TupleRawNames[i],
+				Type:  elem.String(),
+				Value: formatValue(*elem, v.Field(i), bytesAsString),
+			}
+		}
+		return fields
+	}
+	return v.Interface()
+}
@@ -0,0 +1,195 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/config"
+	"ethbaas/internal/log"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// a chain node of a project
+type Node struct {
+	Proj   string
+	Index  int
+	Port   int32
+	WsPort int32
+}
+
+func (n Node) String() string {
+	return fmt.Sprintf("%s/node%d", n.Proj, n.Index)
+}
+
+type pooledConn struct {
+	conn    *ChainConn
+	checked time.Time
+}
+
+// Pool keeps one connection per project node and transport, connections are
+// health checked before reuse and redialed when broken.
+type Pool struct {
+	mu            sync.Mutex
+	conns         map[string]*pooledConn
+	dialTimeout   time.Duration
+	checkTimeout  time.Duration
+	checkInterval time.Duration
+}
+
+var DefaultPool = NewPool()
+
+func NewPool() *Pool {
+	config.C.SetDefault("rpc.dialtimeout", "10s")
+	config.C.SetDefault("rpc.checktimeout", "3s")
+	config.C.SetDefault("rpc.checkinterval", "30s")
+	p := &Pool{
+		conns:         map[string]*pooledConn{},
+		dialTimeout:   config.C.GetDuration("rpc.dialtimeout"),
+		checkTimeout:  config.C.GetDuration("rpc.checktimeout"),
+		checkInterval: config.C.GetDuration("rpc.checkinterval"),
+	}
+	return p
+}
+
+// get http connection of node
+func (p *Pool) Get(ctx context.Context, node Node) (*ChainConn, error) {
+	url := fmt.Sprintf("%s:%d", config.C.GetString("ethurl"), node.Port)
+	return p.get(ctx, node.String(), url)
+}
+
+// get websocket connection of node
+func (p *Pool) GetWS(ctx context.Context, node Node) (*ChainConn, error) {
+	if node.WsPort == 0 {
+		return nil, fmt.Errorf("Node %s has no websocket port", node)
+	}
+	url := fmt.Sprintf("%s:%d", config.C.GetString("ethwsurl"), node.WsPort)
+	return p.get(ctx, node.String()+"/ws", url)
+}
+
+// run fn with node connection, redial and retry once when connection broken
+func (p *Pool) Do(ctx context.Context, node Node, fn func(*ChainConn) error) error {
+	conn, err := p.Get(ctx, node)
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	if !IsConnError(err) {
+		return err
+	}
+
+	log.Logger.Warnf("Connection to %s broken, reconnecting: %s", node, err)
+	p.Invalidate(node)
+	conn, err = p.Get(ctx, node)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// drop node connections, next get will redial
+func (p *Pool) Invalidate(node Node) {
+	p.invalidate(node.String(), node.String()+"/ws")
+}
+
+// drop the http connection of node only, subscriptions on ws stay open
+func (p *Pool) invalidateHTTP(node Node) {
+	p.invalidate(node.String())
+}
+
+func (p *Pool) invalidate(keys ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range keys {
+		if pc, ok := p.conns[key]; ok {
+			pc.conn.Close()
+			delete(p.conns, key)
+		}
+	}
+}
+
+// close all connections
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, pc := range p.conns {
+		pc.conn.Close()
+		delete(p.conns, key)
+	}
+}
+
+func (p *Pool) get(ctx context.Context, key, url string) (*ChainConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	pc, ok := p.conns[key]
+	fresh := ok && time.Since(pc.checked) < p.checkInterval
+	p.mu.Unlock()
+
+	if fresh {
+		return pc.conn, nil
+	}
+	if ok {
+		// the connection is shared, its check must not fail with the caller's ctx
+		err := p.check(pc.conn)
+		if err == nil {
+			p.mu.Lock()
+			pc.checked = time.Now()
+			p.mu.Unlock()
+			return pc.conn, nil
+		}
+
+		log.Logger.Warnf("Connection %s unhealthy, redialing: %s", key, err)
+		p.mu.Lock()
+		if p.conns[key] == pc {
+			pc.conn.Close()
+			delete(p.conns, key)
+		}
+		p.mu.Unlock()
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, p.dialTimeout)
+	defer cancel()
+	conn, err := dialContext(dialCtx, url)
+	if err != nil {
+		return nil, fmt.Errorf("Dial %s failed: %w", key, err)
+	}
+	// http dial is lazy, make sure the node answers
+	if err := p.check(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Node %s unavailable: %w", key, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if exist, ok := p.conns[key]; ok {
+		// another caller dialed in the meantime
+		conn.Close()
+		return exist.conn, nil
+	}
+	p.conns[key] = &pooledConn{conn: conn, checked: time.Now()}
+	return conn, nil
+}
+
+// health check with its own timeout, independent of any caller
+func (p *Pool) check(conn *ChainConn) error {
+	checkCtx, cancel := context.WithTimeout(context.Background(), p.checkTimeout)
+	defer cancel()
+	_, err := conn.EthCli.BlockNumber(checkCtx)
+	return err
+}
+
+// whether err is a transport failure rather than an rpc error,
+// timeouts are excluded since the request may have been processed
+func IsConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return !netErr.Timeout()
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/config"
+	"ethbaas/internal/model"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// poll receipt of tx until it is mined or ctx is done
+func WaitReceipt(ctx context.Context, conn *ChainConn, hash common.Hash) (*types.Receipt, error) {
+	config.C.SetDefault("tx.pollinterval", "1s")
+	ticker := time.NewTicker(config.C.GetDuration("tx.pollinterval"))
+	defer ticker.Stop()
+
+	for {
+		receipt, err := conn.EthCli.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// result of a sent tx, receipt fields are filled when receipt is not nil
+func NewTxResult(ctx context.Context, conn *ChainConn, tx *types.Transaction, receipt *types.Receipt) *model.TxResult {
+	res := &model.TxResult{
+		Hash: tx.Hash().Hex(),
+	}
+	if receipt == nil {
+		return res
+	}
+	res.Mined = true
+	res.Status = receipt.Status
+	res.BlockNumber = receipt.BlockNumber.Uint64()
+	res.GasUsed = receipt.GasUsed
+	if receipt.ContractAddress != (common.Address{}) {
+		res.Contract = receipt.ContractAddress.Hex()
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		res.RevertReason = ReplayRevert(ctx, conn, tx, receipt)
+	}
+	return res
+}
+
+// revert reason of a failed tx, found by replaying it as a call on the
+// state before its block
+func ReplayRevert(ctx context.Context, conn *ChainConn, tx *types.Transaction, receipt *types.Receipt) string {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return ""
+	}
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, err = conn.EthCli.CallContract(ctx, msg, parent)
+	if err == nil {
+		// out of gas does not revert on replay with the same limit
+		if receipt.GasUsed == tx.Gas() {
+			return "out of gas"
+		}
+		return ""
+	}
+	if reason, ok := RevertReason(err); ok {
+		return reason
+	}
+	return err.Error()
+}
+
+// result of a sent tx, waits for the receipt when wait is set; a reverted
+// tx is returned as error along with its result
+func Outcome(ctx context.Context, conn *ChainConn, tx *types.Transaction, wait bool) (*model.TxResult, error) {
+	if !wait {
+		return NewTxResult(ctx, conn, tx, nil), nil
+	}
+	receipt, err := WaitReceipt(ctx, conn, tx.Hash())
+	if err != nil {
+		return NewTxResult(ctx, conn, tx, nil), fmt.Errorf("Waiting receipt of %s: %w", tx.Hash().Hex(), err)
+	}
+	res := NewTxResult(ctx, conn, tx, receipt)
+	if res.Failed() {
+		return res, fmt.Errorf("Tx %s reverted in block %d: %s", res.Hash, res.BlockNumber, res.RevertReason)
+	}
+	return res, nil
+}
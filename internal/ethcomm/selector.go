@@ -0,0 +1,169 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/config"
+	"ethbaas/internal/log"
+	"fmt"
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// let the selector choose the node
+const AnyNode = -1
+
+const (
+	RoundRobin = "roundrobin"
+	LeastLag   = "leastlag"
+)
+
+type nodeState struct {
+	node   Node
+	conn   *ChainConn
+	height uint64
+}
+
+// Selector picks healthy and synced nodes of a project for rpc calls.
+type Selector struct {
+	pool        *Pool
+	strategy    string
+	maxLag      uint64
+	readTimeout time.Duration
+	next        uint32
+}
+
+var DefaultSelector = NewSelector(DefaultPool)
+
+func NewSelector(pool *Pool) *Selector {
+	config.C.SetDefault("rpc.selector", RoundRobin)
+	config.C.SetDefault("rpc.maxlag", 2)
+	config.C.SetDefault("rpc.readtimeout", "10s")
+	s := &Selector{
+		pool:        pool,
+		strategy:    config.C.GetString("rpc.selector"),
+		maxLag:      config.C.GetUint64("rpc.maxlag"),
+		readTimeout: config.C.GetDuration("rpc.readtimeout"),
+	}
+	return s
+}
+
+// pick a node connection, pin >= 0 selects the node explicitly
+func (s *Selector) Pick(ctx context.Context, nodes []Node, pin int) (Node, *ChainConn, error) {
+	states, err := s.candidates(ctx, nodes, pin)
+	if err != nil {
+		return Node{}, nil, err
+	}
+	return states[0].node, states[0].conn, nil
+}
+
+// run read fn on a picked node, fall back to the other candidates when the
+// node fails to answer or hangs past rpc.readtimeout, pinned nodes are never
+// replaced. fn must use the ctx it is given
+func (s *Selector) Read(ctx context.Context, nodes []Node, pin int, fn func(context.Context, *ChainConn) error) error {
+	states, err := s.candidates(ctx, nodes, pin)
+	if err != nil {
+		return err
+	}
+	for i, st := range states {
+		// the last candidate has the whole deadline of ctx
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if i+1 < len(states) && s.readTimeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, s.readTimeout)
+		}
+		err = fn(actx, st.conn)
+		cancel()
+		if err == nil || !isNodeFailure(err) || ctx.Err() != nil {
+			return err
+		}
+		s.pool.invalidateHTTP(st.node)
+		if i+1 < len(states) {
+			log.Logger.Warnf("Read on %s failed, retrying on %s: %s", st.node, states[i+1].node, err)
+		}
+	}
+	return err
+}
+
+// healthy and synced nodes in preferred order
+func (s *Selector) candidates(ctx context.Context, nodes []Node, pin int) ([]nodeState, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("No node available")
+	}
+	if pin >= 0 {
+		if pin >= len(nodes) {
+			return nil, fmt.Errorf("Node index %d out of range, project has %d nodes", pin, len(nodes))
+		}
+		conn, err := s.pool.Get(ctx, nodes[pin])
+		if err != nil {
+			return nil, err
+		}
+		return []nodeState{{node: nodes[pin], conn: conn}}, nil
+	}
+
+	// rotate start node for round robin
+	start := int(atomic.AddUint32(&s.next, 1)-1) % len(nodes)
+	states := []nodeState{}
+	var highest uint64
+	var lastErr error
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[(start+i)%len(nodes)]
+		st, err := s.state(ctx, node)
+		if err != nil {
+			log.Logger.Warnf("Skip node %s: %s", node, err)
+			lastErr = err
+			continue
+		}
+		if st.height > highest {
+			highest = st.height
+		}
+		states = append(states, st)
+	}
+	if len(states) == 0 {
+		return nil, fmt.Errorf("No healthy node: %w", lastErr)
+	}
+
+	synced := []nodeState{}
+	for _, st := range states {
+		if highest-st.height <= s.maxLag {
+			synced = append(synced, st)
+		}
+	}
+	if s.strategy == LeastLag {
+		sort.SliceStable(synced, func(i, j int) bool {
+			return synced[i].height > synced[j].height
+		})
+	}
+	return synced, nil
+}
+
+func (s *Selector) state(ctx context.Context, node Node) (nodeState, error) {
+	conn, err := s.pool.Get(ctx, node)
+	if err != nil {
+		return nodeState{}, err
+	}
+	sp, err := conn.EthCli.SyncProgress(ctx)
+	if err != nil {
+		return nodeState{}, err
+	}
+	if sp != nil {
+		return nodeState{}, fmt.Errorf("node is syncing %d/%d", sp.CurrentBlock, sp.HighestBlock)
+	}
+	height, err := conn.EthCli.BlockNumber(ctx)
+	if err != nil {
+		return nodeState{}, err
+	}
+	return nodeState{node: node, conn: conn, height: height}, nil
+}
+
+// transport failures and timeouts only; anything the node answered,
+// including not found, missing code or undecodable results, goes back to the
+// caller
+func isNodeFailure(err error) bool {
+	if IsConnError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
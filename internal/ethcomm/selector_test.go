@@ -0,0 +1,40 @@
+package ethcomm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+type testNetError struct{ timeout bool }
+
+func (e testNetError) Error() string   { return "net error" }
+func (e testNetError) Timeout() bool   { return e.timeout }
+func (e testNetError) Temporary() bool { return false }
+
+func TestIsNodeFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"net timeout", testNetError{timeout: true}, true},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), true},
+		{"not found", ethereum.NotFound, false},
+		{"rpc error", testRPCError{}, false},
+		{"other", errors.New("abi: cannot unmarshal"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNodeFailure(tt.err); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
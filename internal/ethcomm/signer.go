@@ -0,0 +1,251 @@
+package ethcomm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"ethbaas/internal/config"
+	"ethbaas/internal/log"
+	"ethbaas/internal/model"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	SignerLocal = "local"
+	SignerClef  = "clef"
+)
+
+// Signer signs transactions of one account.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error)
+}
+
+// signer holding a private key in memory
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainId), s.key)
+}
+
+// signer backed by an unlocked keystore account
+type KeystoreSigner struct {
+	ks  *keystore.KeyStore
+	acc accounts.Account
+}
+
+func NewKeystoreSigner(ks *keystore.KeyStore, acc accounts.Account, passphrase string) (*KeystoreSigner, error) {
+	if err := ks.Unlock(acc, passphrase); err != nil {
+		return nil, err
+	}
+	return &KeystoreSigner{ks: ks, acc: acc}, nil
+}
+
+func (s *KeystoreSigner) Address() common.Address {
+	return s.acc.Address
+}
+
+func (s *KeystoreSigner) SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	return s.ks.SignTx(s.acc, tx, chainId)
+}
+
+// signer delegating to an external Clef instance, keys never enter ethbaas
+type ExternalSigner struct {
+	cli     *rpc.Client
+	address common.Address
+}
+
+func NewExternalSigner(url string, address common.Address) (*ExternalSigner, error) {
+	cli, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalSigner{cli: cli, address: address}, nil
+}
+
+func (s *ExternalSigner) Address() common.Address {
+	return s.address
+}
+
+// arguments of clef account_signTransaction
+type signTxArgs struct {
+	From                 common.MixedcaseAddress  `json:"from"`
+	To                   *common.MixedcaseAddress `json:"to"`
+	Gas                  hexutil.Uint64           `json:"gas"`
+	GasPrice             *hexutil.Big             `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big             `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big             `json:"maxPriorityFeePerGas,omitempty"`
+	Value                hexutil.Big              `json:"value"`
+	Nonce                hexutil.Uint64           `json:"nonce"`
+	Data                 hexutil.Bytes            `json:"data"`
+	ChainID              *hexutil.Big             `json:"chainId,omitempty"`
+}
+
+type signTxResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func (s *ExternalSigner) SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	args := signTxArgs{
+		From:    common.NewMixedcaseAddress(s.address),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainId),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	res := signTxResult{}
+	if err := s.cli.CallContext(ctx, &res, "account_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("External signer rejected tx: %w", err)
+	}
+	signTx := &types.Transaction{}
+	if err := signTx.UnmarshalBinary(res.Raw); err != nil {
+		return nil, err
+	}
+	return signTx, nil
+}
+
+var (
+	signersMu sync.Mutex
+	signers   = map[string]Signer{}
+)
+
+// load the signer of account from, defaults to the configured account and
+// then the deprecated adminpk; signers are cached so keys unlock once
+func LoadSigner(from string) (Signer, error) {
+	return loadSigner(from, true)
+}
+
+// signer of the sender of a tx, with opts.NoPrompt the keystore passphrase
+// must be set in the environment
+func TxSigner(opts model.TxOptions) (Signer, error) {
+	return loadSigner(opts.From, !opts.NoPrompt)
+}
+
+func loadSigner(from string, prompt bool) (Signer, error) {
+	if from == "" {
+		from = config.C.GetString("account")
+	}
+	config.C.SetDefault("signer.type", SignerLocal)
+	signerType := config.C.GetString("signer.type")
+
+	key := signerType + "/" + strings.ToLower(from)
+	signersMu.Lock()
+	s, ok := signers[key]
+	signersMu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	// built unlocked, the passphrase prompt must not block other signers
+	s, err := newSigner(signerType, from, prompt)
+	if err != nil {
+		return nil, err
+	}
+	signersMu.Lock()
+	defer signersMu.Unlock()
+	if cached, ok := signers[key]; ok {
+		return cached, nil
+	}
+	signers[key] = s
+	return s, nil
+}
+
+// uncached signer of from, the passphrase is prompted for only when prompt is set
+func newSigner(signerType, from string, prompt bool) (Signer, error) {
+	var s Signer
+	switch {
+	case signerType == SignerClef:
+		if !common.IsHexAddress(from) {
+			return nil, fmt.Errorf("External signer requires an account address, got %q", from)
+		}
+		es, err := NewExternalSigner(config.C.GetString("signer.url"), common.HexToAddress(from))
+		if err != nil {
+			return nil, err
+		}
+		s = es
+	case signerType != SignerLocal:
+		return nil, fmt.Errorf("Unknown signer type: %s", signerType)
+	case from == "":
+		log.Logger.Warn("Signing with adminpk is deprecated, import it with 'account import' and use --from")
+		privateKey, err := GetAdminPk()
+		if err != nil {
+			return nil, err
+		}
+		s = NewKeySigner(privateKey)
+	default:
+		ks := OpenKeystore()
+		acc, err := FindAccount(ks, from)
+		if err != nil {
+			return nil, err
+		}
+		pass, err := EnvPassphrase()
+		if err != nil && prompt {
+			pass, err = Passphrase(fmt.Sprintf("Passphrase of %s: ", acc.Address.Hex()))
+		}
+		if err != nil {
+			return nil, err
+		}
+		kss, err := NewKeystoreSigner(ks, acc, pass)
+		if err != nil {
+			return nil, err
+		}
+		s = kss
+	}
+	return s, nil
+}
+
+// bind transact opts signing through signer, signing errors are NotSent so
+// that the nonce of the unsent tx is released
+func NewTransactor(ctx context.Context, signer Signer, chainId *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: signer.Address(),
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != signer.Address() {
+				return nil, NotSent(bind.ErrNotAuthorized)
+			}
+			signTx, err := signer.SignTx(ctx, tx, chainId)
+			if err != nil {
+				return nil, NotSent(err)
+			}
+			return signTx, nil
+		},
+		Context: ctx,
+	}
+}
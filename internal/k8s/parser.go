@@ -11,6 +11,9 @@ import (
 
 type KV map[string]interface{}
 
+// name of the websocket port of node services
+const WsPortName = "websoket"
+
 type Parser struct{}
 
 func NewParser() *Parser {
@@ -29,29 +32,43 @@ func (p *Parser) Parse(proj *model.Project) error {
 	if err := p.genConfigMap(proj); err != nil {
 		return err
 	}
-	if err := p.genPv(proj); err != nil {
+	nodes := []int{}
+	for i := 0; i < proj.NodeCount; i++ {
+		nodes = append(nodes, i)
+	}
+	return p.ParseNodes(proj, nodes...)
+}
+
+// generate yaml of the given nodes only, files of other nodes stay as they are
+func (p *Parser) ParseNodes(proj *model.Project, nodes ...int) error {
+	if err := p.genPv(proj, nodes); err != nil {
 		return err
 	}
-	if err := p.genPvc(proj); err != nil {
+	if err := p.genPvc(proj, nodes); err != nil {
 		return err
 	}
-	if err := p.genDeploy(proj); err != nil {
+	if err := p.genDeploy(proj, nodes); err != nil {
 		return err
 	}
-	if err := p.genSvc(proj); err != nil {
+	if err := p.genSvc(proj, nodes); err != nil {
 		return err
 	}
 	return nil
 }
 
+// generate service yaml of node i only, e.g. to pin its node ports
+func (p *Parser) ParseSvc(proj *model.Project, i int) error {
+	return p.genSvc(proj, []int{i})
+}
+
 // generate proj home dir
 func (p *Parser) genProjHome(proj *model.Project) error {
 	return os.MkdirAll(proj.Home(), os.ModePerm)
 }
 
 // generate pv yaml
-func (p *Parser) genPv(proj *model.Project) error {
-	for i := 0; i < proj.NodeCount; i++ {
+func (p *Parser) genPv(proj *model.Project, nodes []int) error {
+	for _, i := range nodes {
 		pv := map[string]interface{}{
 			"apiVersion": "v1",
 			"kind":       "PersistentVolume",
@@ -84,8 +101,8 @@ func (p *Parser) genPv(proj *model.Project) error {
 }
 
 // generate pvc yaml
-func (p *Parser) genPvc(proj *model.Project) error {
-	for i := 0; i < proj.NodeCount; i++ {
+func (p *Parser) genPvc(proj *model.Project, nodes []int) error {
+	for _, i := range nodes {
 		pvc := map[string]interface{}{
 			"apiVersion": "v1",
 			"kind":       "PersistentVolumeClaim",
@@ -120,8 +137,8 @@ func (p *Parser) genPvc(proj *model.Project) error {
 }
 
 // generate deployment yaml
-func (p *Parser) genDeploy(proj *model.Project) error {
-	for i := 0; i < proj.NodeCount; i++ {
+func (p *Parser) genDeploy(proj *model.Project, nodes []int) error {
+	for _, i := range nodes {
 		nodeLabel := fmt.Sprintf("ethbaas-node%d", i)
 		deploy := map[string]interface{}{
 			"apiVersion": "apps/v1",
@@ -207,6 +224,10 @@ func (p *Parser) genDeploy(proj *model.Project) error {
 									"--http.api=admin,web3,eth,net,debug,personal",
 									"--http.corsdomain=*",
 									"--http.addr=0.0.0.0",
+									"--ws",
+									"--ws.api=web3,eth,net",
+									"--ws.origins=*",
+									"--ws.addr=0.0.0.0",
 									"--rpc.allow-unprotected-txs",
 									"--gcmode=archive",
 									"--nodiscover",
@@ -256,8 +277,18 @@ func (p *Parser) genDeploy(proj *model.Project) error {
 }
 
 // generate service yaml
-func (p *Parser) genSvc(proj *model.Project) error {
-	for i := 0; i < proj.NodeCount; i++ {
+func (p *Parser) genSvc(proj *model.Project, nodes []int) error {
+	for _, i := range nodes {
+		// k8s assigns the ws node port when it is unknown
+		wsPort := map[string]interface{}{
+			"name":       WsPortName,
+			"targetPort": 8546,
+			"port":       8546,
+			"protocol":   "TCP",
+		}
+		if port := proj.WsNodePort(i); port != 0 {
+			wsPort["nodePort"] = port
+		}
 		svc := map[string]interface{}{
 			"apiVersion": "v1",
 			"kind":       "Service",
@@ -279,14 +310,9 @@ func (p *Parser) genSvc(proj *model.Project) error {
 						"targetPort": 8545,
 						"port":       8545,
 						"protocol":   "TCP",
-						"nodePort":   proj.FirstNodePort + int32(i),
-					},
-					map[string]interface{}{
-						"name":       "websoket",
-						"targetPort": 8546,
-						"port":       8546,
-						"protocol":   "TCP",
+						"nodePort":   proj.NodePort(i),
 					},
+					wsPort,
 					map[string]interface{}{
 						"name":       "p2p1",
 						"targetPort": 30303,
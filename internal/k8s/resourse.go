@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -67,7 +68,8 @@ func Apply(file string) error {
 			dri = dd.Resource(mapping.Resource)
 		}
 
-		if _, err := dri.Create(context.Background(), unstructuredObj, metav1.CreateOptions{}); err != nil {
+		// existing resources count as applied, so a failed start can be retried
+		if _, err := dri.Create(context.Background(), unstructuredObj, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
 			return err
 		}
 	}
@@ -119,27 +121,31 @@ func Delete(file string) error {
 		} else {
 			dri = dd.Resource(mapping.Resource)
 		}
-		if err := dri.Delete(context.Background(), unstructuredObj.GetName(), metav1.DeleteOptions{}); err != nil {
+		// resources may be gone already, e.g. with their namespace
+		if err := dri.Delete(context.Background(), unstructuredObj.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 			return err
 		}
 	}
 	return nil
 }
 
-func GetPods(namespace string) ([]Pod, error) {
+func GetPods(ctx context.Context, namespace string) ([]Pod, error) {
 	clientset, _, err := getClient()
 	if err != nil {
 		return nil, err
 	}
 	podList, err := clientset.CoreV1().
 		Pods(namespace).
-		List(context.Background(), metav1.ListOptions{})
+		List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 	svcList, err := clientset.CoreV1().
 		Services(namespace).
-		List(context.Background(), metav1.ListOptions{})
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
 
 	svcs := map[string]Pod{}
 	for _, svc := range svcList.Items {
@@ -189,6 +195,23 @@ func GetSvcs(namespace string) ([]v1.Service, error) {
 	return svcList.Items, nil
 }
 
+// node ports of the port portName of the services in namespace, by service name
+func SvcNodePorts(namespace, portName string) (map[string]int32, error) {
+	svcs, err := GetSvcs(namespace)
+	if err != nil {
+		return nil, err
+	}
+	ports := map[string]int32{}
+	for _, svc := range svcs {
+		for _, p := range svc.Spec.Ports {
+			if p.Name == portName {
+				ports[svc.Name] = p.NodePort
+			}
+		}
+	}
+	return ports, nil
+}
+
 func getClient() (*kubernetes.Clientset, dynamic.Interface, error) {
 	home := homedir.HomeDir()
 	configPath := filepath.Join(home, ".kube", "config")
@@ -0,0 +1,78 @@
+package model
+
+// block as shown by the explorer, values in wei are decimal strings
+type Block struct {
+	Number       uint64   `json:"number"`
+	Hash         string   `json:"hash"`
+	ParentHash   string   `json:"parentHash"`
+	Time         uint64   `json:"time"`
+	Miner        string   `json:"miner"`
+	Difficulty   string   `json:"difficulty"`
+	GasUsed      uint64   `json:"gasUsed"`
+	GasLimit     uint64   `json:"gasLimit"`
+	BaseFee      string   `json:"baseFee,omitempty"`
+	Size         uint64   `json:"size"`
+	Transactions []string `json:"transactions"`
+}
+
+// transaction as shown by the explorer, block fields are null while pending
+type Transaction struct {
+	Hash        string  `json:"hash"`
+	Type        uint8   `json:"type"`
+	From        string  `json:"from"`
+	To          string  `json:"to,omitempty"`
+	Value       string  `json:"value"`
+	Nonce       uint64  `json:"nonce"`
+	Gas         uint64  `json:"gas"`
+	GasPrice    string  `json:"gasPrice"`
+	GasTipCap   string  `json:"maxPriorityFeePerGas,omitempty"`
+	GasFeeCap   string  `json:"maxFeePerGas,omitempty"`
+	Input       string  `json:"input"`
+	Pending     bool    `json:"pending"`
+	BlockNumber *uint64 `json:"blockNumber"`
+	BlockHash   *string `json:"blockHash"`
+	TxIndex     *uint64 `json:"transactionIndex"`
+}
+
+type Receipt struct {
+	TxHash            string `json:"transactionHash"`
+	Status            uint64 `json:"status"`
+	BlockNumber       uint64 `json:"blockNumber"`
+	BlockHash         string `json:"blockHash"`
+	TxIndex           uint   `json:"transactionIndex"`
+	GasUsed           uint64 `json:"gasUsed"`
+	CumulativeGasUsed uint64 `json:"cumulativeGasUsed"`
+	ContractAddress   string `json:"contractAddress,omitempty"`
+	Logs              []Log  `json:"logs"`
+}
+
+type Log struct {
+	Address  string   `json:"address"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+	LogIndex uint     `json:"logIndex"`
+	Removed  bool     `json:"removed"`
+}
+
+// account state at a block
+type Account struct {
+	Address     string `json:"address"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Balance     string `json:"balance"`
+	Nonce       uint64 `json:"nonce"`
+	Contract    bool   `json:"contract"`
+	Code        string `json:"code"`
+}
+
+// chain node of a project with its pod and sync state, Error is set when the
+// node can not be reached
+type NodeStatus struct {
+	Index       int    `json:"index"`
+	Port        int32  `json:"port"`
+	WsPort      int32  `json:"wsPort"`
+	Pod         string `json:"pod"`
+	PodStatus   string `json:"podStatus"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Peers       uint64 `json:"peers"`
+	Error       string `json:"error,omitempty"`
+}
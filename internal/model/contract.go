@@ -1,7 +1,100 @@
 package model
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
 type Contract struct {
 	Name string
 	ABI  string
 	BIN  string
+	// solidity source and compiler settings, set when compiled by ethbaas
+	Source   string
+	Compiler string
+	Optimize bool
+	Runs     int
+}
+
+// decoded abi value
+type Field struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
+// decoded abi values in declaration order, marshaled as a JSON object
+type Fields []Field
+
+func (fs Fields) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, f := range fs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// contract events to read, of all events when Event is empty
+type EventQuery struct {
+	Event string
+	// name=value filters of indexed args of Event
+	Filters []string
+	// latest block when ToBlock is nil
+	FromBlock *big.Int
+	ToBlock   *big.Int
+	// render fixed bytes as trimmed text
+	BytesAsString bool
+}
+
+// decoded contract event log
+type Event struct {
+	Event       string `json:"event"`
+	Address     string `json:"address"`
+	BlockNumber uint64 `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	TxHash      string `json:"txHash"`
+	TxIndex     uint   `json:"txIndex"`
+	LogIndex    uint   `json:"logIndex"`
+	Removed     bool   `json:"removed"`
+	Args        Fields `json:"args"`
+}
+
+// position of the event in the chain, block:logIndex
+func (e *Event) ID() string {
+	return fmt.Sprintf("%d:%d", e.BlockNumber, e.LogIndex)
+}
+
+// parse an event id made by Event.ID
+func ParseEventID(id string) (uint64, uint, error) {
+	parts := strings.Split(id, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid event id %q, expected block:logIndex", id)
+	}
+	block, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid event id %q: %w", id, err)
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid event id %q: %w", id, err)
+	}
+	return block, uint(index), nil
 }
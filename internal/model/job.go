@@ -0,0 +1,23 @@
+package model
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobSuccess = "success"
+	JobFailed  = "failed"
+)
+
+// long running project operation, polled by id
+type Job struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Proj    string `json:"proj"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Created int64  `json:"created"`
+	Updated int64  `json:"updated"`
+}
+
+func (j *Job) Done() bool {
+	return j.Status == JobSuccess || j.Status == JobFailed
+}
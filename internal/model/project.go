@@ -11,6 +11,9 @@ type Project struct {
 	Name          string
 	NodeCount     int
 	FirstNodePort int32
+	// node ports of existing nodes, follow FirstNodePort when empty
+	Ports   []int32
+	WsPorts []int32
 }
 
 func (p *Project) Home() string {
@@ -49,10 +52,35 @@ func (p *Project) DeployFile(i int) string {
 	return filepath.Join(p.Home(), fmt.Sprintf("deploy_%d.yaml", i))
 }
 
+// http node port of node i
+func (p *Project) NodePort(i int) int32 {
+	if i < len(p.Ports) {
+		return p.Ports[i]
+	}
+	return p.FirstNodePort + int32(i)
+}
+
+// websocket node port of node i, 0 when unknown: projects created before
+// ws ports were allocated got them assigned by k8s
+func (p *Project) WsNodePort(i int) int32 {
+	if i < len(p.WsPorts) {
+		return p.WsPorts[i]
+	}
+	return 0
+}
+
+func (p *Project) WsPort2Str() string {
+	ports := []string{}
+	for i := 0; i < p.NodeCount; i++ {
+		ports = append(ports, fmt.Sprintf("%d", p.WsNodePort(i)))
+	}
+	return strings.Join(ports, ",")
+}
+
 func (p *Project) Port2Str() string {
 	ports := []string{}
-	for i, start := 0, p.FirstNodePort; i < p.NodeCount; i, start = i+1, start+1 {
-		ports = append(ports, fmt.Sprintf("%d", start))
+	for i := 0; i < p.NodeCount; i++ {
+		ports = append(ports, fmt.Sprintf("%d", p.NodePort(i)))
 	}
 	return strings.Join(ports, ",")
 }
@@ -0,0 +1,34 @@
+package model
+
+import "math/big"
+
+// options of sending transactions
+type TxOptions struct {
+	// keystore account address, empty for the default account
+	From string
+	// send legacy gas price txs on london enabled chains
+	Legacy bool
+	// fixed gas limit, estimated when zero
+	GasLimit uint64
+	// wait for the receipt
+	Wait bool
+	// wei sent along, nil for none
+	Value *big.Int
+	// fail instead of prompting for the keystore passphrase, e.g. on servers
+	NoPrompt bool
+}
+
+// sent transaction, receipt fields are set once mined
+type TxResult struct {
+	Hash         string `json:"hash"`
+	Mined        bool   `json:"mined"`
+	Status       uint64 `json:"status"`
+	BlockNumber  uint64 `json:"blockNumber"`
+	GasUsed      uint64 `json:"gasUsed"`
+	Contract     string `json:"contractAddress,omitempty"`
+	RevertReason string `json:"revertReason,omitempty"`
+}
+
+func (r *TxResult) Failed() bool {
+	return r.Mined && r.Status == 0
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"ethbaas/internal/ethcomm"
+	"ethbaas/internal/model"
+	"ethbaas/internal/server/message"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// active contracts, ?proj= limits them to one project
+func (c *Controller) ContractList(ctx *gin.Context) {
+	total, list, err := c.contractSvc.List(ctx.Query("proj"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponsePage(ctx, list, total)
+}
+
+// call a constant method, the result is the decoded outputs
+func (c *Controller) ContractCall(ctx *gin.Context) {
+	req := &message.ContractCall{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	args, err := argsInput(req.Args)
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	outputs, err := c.contractSvc.Call(ctx.Request.Context(), req.Proj, ctx.Param("name"), ctx.Param("method"), args, nodeIndex(req.Node), req.BytesAsString)
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, outputs)
+}
+
+// send a tx calling method, the result holds the tx hash and, when waited
+// for, its receipt status
+func (c *Controller) ContractSend(ctx *gin.Context) {
+	req := &message.ContractSend{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	args, err := argsInput(req.Args)
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	txOpts := model.TxOptions{
+		From:     req.From,
+		Legacy:   req.Legacy,
+		GasLimit: req.GasLimit,
+		Wait:     req.Wait,
+		NoPrompt: true,
+	}
+	if req.Value != "" {
+		value, ok := new(big.Int).SetString(req.Value, 10)
+		if !ok || value.Sign() < 0 {
+			ResponseFail(ctx, fmt.Sprintf("Invalid value: %s", req.Value))
+			return
+		}
+		txOpts.Value = value
+	}
+	result, err := c.contractSvc.Send(ctx.Request.Context(), req.Proj, ctx.Param("name"), ctx.Param("method"), args, nodeIndex(req.Node), txOpts)
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, result)
+}
+
+// method args as parsed by ethcomm.ParseArgs, from a JSON array or a string
+// of comma separated values
+func argsInput(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", nil
+	}
+	switch raw[0] {
+	case '[':
+		return string(raw), nil
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+	return "", fmt.Errorf("Args must be a JSON array or a string")
+}
+
+func nodeIndex(node *int) int {
+	if node == nil {
+		return ethcomm.AnyNode
+	}
+	return *node
+}
+
+// keep idle event streams open through proxies
+const sseKeepAlive = 15 * time.Second
+
+// stream decoded contract events as server sent events with id block:logIndex;
+// clients resume after the Last-Event-ID header, or from the from_block query
+func (c *Controller) ContractEventStream(ctx *gin.Context) {
+	q := model.EventQuery{
+		Event:         ctx.Query("event"),
+		Filters:       ctx.QueryArray("filter"),
+		BytesAsString: ctx.Query("bytes_as_string") == "true",
+	}
+	if s := ctx.Query("from_block"); s != "" {
+		block, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			ResponseFail(ctx, fmt.Sprintf("Invalid from_block: %s", s))
+			return
+		}
+		q.FromBlock = new(big.Int).SetUint64(block)
+	}
+	after := ctx.GetHeader("Last-Event-ID")
+	if after == "" {
+		after = ctx.Query("last_event_id")
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(200)
+	ctx.Writer.Flush()
+
+	reqCtx, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+	events := make(chan *model.Event)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.contractSvc.Subscribe(reqCtx, ctx.Query("proj"), ctx.Param("name"), q, after, func(e *model.Event) error {
+			select {
+			case events <- e:
+				return nil
+			case <-reqCtx.Done():
+				return reqCtx.Err()
+			}
+		})
+	}()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-events:
+			ctx.Render(-1, sse.Event{Id: e.ID(), Event: e.Event, Data: e})
+			ctx.Writer.Flush()
+		case err := <-errc:
+			if err != nil && reqCtx.Err() == nil {
+				ctx.Render(-1, sse.Event{Event: "error", Data: err.Error()})
+				ctx.Writer.Flush()
+			}
+			return
+		case <-ticker.C:
+			ctx.Writer.WriteString(": keepalive\n\n")
+			ctx.Writer.Flush()
+		case <-reqCtx.Done():
+			return
+		}
+	}
+}
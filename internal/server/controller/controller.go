@@ -1,25 +1,38 @@
 package controller
 
 import (
+	"context"
 	"ethbaas/internal/db"
 	"ethbaas/internal/server/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Controller struct {
-	dbClient *db.Client
-	storeSvc *service.StoreSvc
+	dbClient    *db.Client
+	txSvc       *service.TxSvc
+	contractSvc *service.ContractSvc
+	explorerSvc *service.ExplorerSvc
+	projectSvc  *service.ProjectSvc
 }
 
 func NewController(dbClient *db.Client) *Controller {
 	c := &Controller{
-		dbClient: dbClient,
-		storeSvc: service.NewStoreSvc(dbClient),
+		dbClient:    dbClient,
+		txSvc:       service.NewTxSvc(dbClient),
+		contractSvc: service.NewContractSvc(dbClient),
+		explorerSvc: service.NewExplorerSvc(dbClient),
+		projectSvc:  service.NewProjectSvc(dbClient, service.NewJobManager()),
 	}
 	return c
 }
 
+// resolve pending txs in background until ctx is done
+func (c *Controller) RunTxUpdater(ctx context.Context, interval time.Duration) {
+	c.txSvc.Run(ctx, interval)
+}
+
 func (c *Controller) Health(ctx *gin.Context) {
 	ResponseSuccess(ctx, "Great!")
 }
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// latest blocks, ?limit=N with a default of 10
+func (c *Controller) ExplorerBlocks(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil {
+		ResponseFail(ctx, fmt.Sprintf("Invalid limit: %s", ctx.Query("limit")))
+		return
+	}
+	list, err := c.explorerSvc.Blocks(ctx.Request.Context(), ctx.Param("proj"), limit)
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, list)
+}
+
+// block by number, hash or latest
+func (c *Controller) ExplorerBlock(ctx *gin.Context) {
+	block, err := c.explorerSvc.Block(ctx.Request.Context(), ctx.Param("proj"), ctx.Param("id"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, block)
+}
+
+func (c *Controller) ExplorerTx(ctx *gin.Context) {
+	tx, err := c.explorerSvc.Transaction(ctx.Request.Context(), ctx.Param("proj"), ctx.Param("hash"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, tx)
+}
+
+func (c *Controller) ExplorerReceipt(ctx *gin.Context) {
+	receipt, err := c.explorerSvc.Receipt(ctx.Request.Context(), ctx.Param("proj"), ctx.Param("hash"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, receipt)
+}
+
+// account state, at ?block=N or latest
+func (c *Controller) ExplorerAccount(ctx *gin.Context) {
+	account, err := c.explorerSvc.Account(ctx.Request.Context(), ctx.Param("proj"), ctx.Param("address"), ctx.Query("block"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, account)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"ethbaas/internal/model"
+	"ethbaas/internal/server/message"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *Controller) ProjectList(ctx *gin.Context) {
+	total, list, err := c.projectSvc.List()
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponsePage(ctx, list, total)
+}
+
+func (c *Controller) ProjectCreate(ctx *gin.Context) {
+	req := &message.ProjectCreate{NodeCount: 1, Port: 30545}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ResponseFailStatus(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	proj, err := c.projectSvc.Create(&model.Project{
+		Name:          req.Name,
+		NodeCount:     req.NodeCount,
+		FirstNodePort: req.Port,
+	})
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ctx.Header("Location", "/api/v1/projects/"+proj.Name)
+	ResponseCreated(ctx, proj)
+}
+
+func (c *Controller) ProjectGet(ctx *gin.Context) {
+	proj, err := c.projectSvc.Get(ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, proj)
+}
+
+// start nodes as an async job
+func (c *Controller) ProjectStart(ctx *gin.Context) {
+	job, err := c.projectSvc.Start(ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseJob(ctx, job)
+}
+
+// stop nodes as an async job
+func (c *Controller) ProjectStop(ctx *gin.Context) {
+	job, err := c.projectSvc.Stop(ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseJob(ctx, job)
+}
+
+// delete a stopped project as an async job
+func (c *Controller) ProjectDelete(ctx *gin.Context) {
+	job, err := c.projectSvc.Delete(ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseJob(ctx, job)
+}
+
+// change the node count as an async job
+func (c *Controller) ProjectScale(ctx *gin.Context) {
+	req := &message.ProjectScale{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ResponseFailStatus(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err := c.projectSvc.Scale(ctx.Param("proj"), req.NodeCount)
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseJob(ctx, job)
+}
+
+func (c *Controller) ProjectPods(ctx *gin.Context) {
+	pods, err := c.projectSvc.Pods(ctx.Request.Context(), ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, pods)
+}
+
+// chain info of each node
+func (c *Controller) ProjectInfo(ctx *gin.Context) {
+	infoes, err := c.projectSvc.Info(ctx.Request.Context(), ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, infoes)
+}
+
+// nodes with pod state, block height and peers
+func (c *Controller) ProjectNodes(ctx *gin.Context) {
+	list, err := c.projectSvc.Nodes(ctx.Request.Context(), ctx.Param("proj"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, list)
+}
+
+func (c *Controller) JobGet(ctx *gin.Context) {
+	job, err := c.projectSvc.Job(ctx.Param("id"))
+	if err != nil {
+		ResponseError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, job)
+}
@@ -1,6 +1,10 @@
 package controller
 
 import (
+	"errors"
+	"ethbaas/internal/model"
+	"ethbaas/internal/server/service"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -55,3 +59,47 @@ func ResponsePage(ctx *gin.Context, data interface{}, total int64) {
 	}
 	ctx.JSON(http.StatusOK, resp)
 }
+
+// fail with status, for endpoints reporting errors by http status code
+func ResponseFailStatus(ctx *gin.Context, status int, msg string) {
+	resp := &Resp{
+		Code: 1,
+		Data: "",
+		Msg:  msg,
+	}
+	ctx.JSON(status, resp)
+}
+
+// fail with the http status of a service error kind
+func ResponseError(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrConflict):
+		status = http.StatusConflict
+	}
+	ResponseFailStatus(ctx, status, err.Error())
+}
+
+func ResponseCreated(ctx *gin.Context, data interface{}) {
+	resp := &Resp{
+		Code: 0,
+		Data: data,
+		Msg:  "",
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// accepted async job, polled at its Location
+func ResponseJob(ctx *gin.Context, job *model.Job) {
+	resp := &Resp{
+		Code: 0,
+		Data: job,
+		Msg:  "",
+	}
+	ctx.Header("Location", fmt.Sprintf("/api/v1/jobs/%s", job.ID))
+	ctx.JSON(http.StatusAccepted, resp)
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (c *Controller) TxList(ctx *gin.Context) {
+	total, list, err := c.txSvc.List(ctx.Query("proj"), ctx.Query("status"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponsePage(ctx, list, total)
+}
+
+func (c *Controller) TxGet(ctx *gin.Context) {
+	tx, err := c.txSvc.Get(ctx.Param("hash"))
+	if err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, tx)
+}
+
+func (c *Controller) TxResend(ctx *gin.Context) {
+	hash := ctx.Param("hash")
+	if err := c.txSvc.Resend(ctx.Request.Context(), hash); err != nil {
+		ResponseFail(ctx, err.Error())
+		return
+	}
+	ResponseSuccess(ctx, hash)
+}
@@ -1,15 +1,19 @@
 package server
 
 import (
+	"context"
 	"ethbaas/internal/config"
 	"ethbaas/internal/db"
 	"ethbaas/internal/log"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"ethbaas/internal/server/controller"
+	"ethbaas/internal/server/web"
+	"ethbaas/pkg/projclient"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,15 +32,49 @@ func (s *Server) Start() {
 	}
 	defer dbClient.Close()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	syncWsPorts(dbClient)
+
 	c := controller.NewController(dbClient)
+	config.C.SetDefault("tx.updateinterval", "15s")
+	go c.RunTxUpdater(ctx, config.C.GetDuration("tx.updateinterval"))
+
 	router := gin.Default()
 	v1 := router.Group("/api/v1")
 	{
 		v1.GET("/health", c.Health)
-		v1.POST("/store/query", c.StoreQuery)
-		v1.POST("/store/write", c.StoreWrite)
+		v1.GET("/txs", c.TxList)
+		v1.GET("/txs/:hash", c.TxGet)
+		v1.POST("/txs/:hash/resend", c.TxResend)
+		v1.GET("/projects", c.ProjectList)
+		v1.POST("/projects", c.ProjectCreate)
+		v1.GET("/projects/:proj", c.ProjectGet)
+		v1.DELETE("/projects/:proj", c.ProjectDelete)
+		v1.POST("/projects/:proj/start", c.ProjectStart)
+		v1.POST("/projects/:proj/stop", c.ProjectStop)
+		v1.POST("/projects/:proj/scale", c.ProjectScale)
+		v1.GET("/projects/:proj/pods", c.ProjectPods)
+		v1.GET("/projects/:proj/info", c.ProjectInfo)
+		v1.GET("/projects/:proj/nodes", c.ProjectNodes)
+		v1.GET("/jobs/:id", c.JobGet)
+		v1.GET("/contracts", c.ContractList)
+		v1.POST("/contracts/:name/call/:method", c.ContractCall)
+		v1.POST("/contracts/:name/send/:method", c.ContractSend)
+		v1.GET("/contracts/:name/events/stream", c.ContractEventStream)
+		v1.GET("/projects/:proj/blocks", c.ExplorerBlocks)
+		v1.GET("/projects/:proj/blocks/:id", c.ExplorerBlock)
+		v1.GET("/projects/:proj/txs/:hash", c.ExplorerTx)
+		v1.GET("/projects/:proj/txs/:hash/receipt", c.ExplorerReceipt)
+		v1.GET("/projects/:proj/accounts/:address", c.ExplorerAccount)
 	}
 
+	router.StaticFS("/ui", web.FS())
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.Redirect(http.StatusFound, "/ui/")
+	})
+
 	port := config.C.GetInt("server.port")
 	router.Run(fmt.Sprintf(":%d", port))
 	log.Logger.Info("Logic server started.")
@@ -46,3 +84,21 @@ func (s *Server) Start() {
 	<-stop
 	log.Logger.Info("Server shutdown.")
 }
+
+// store ws ports of running projects created before they were allocated
+func syncWsPorts(dbClient *db.Client) {
+	projCli := projclient.NewClient(dbClient)
+	_, list, err := projCli.List()
+	if err != nil {
+		log.Logger.Warn("List projects failed: ", err)
+		return
+	}
+	for _, p := range list {
+		if !p.Running {
+			continue
+		}
+		if err := projCli.SyncWsPorts(p.Name); err != nil {
+			log.Logger.Warnf("Read ws ports of %s failed: %s", p.Name, err)
+		}
+	}
+}
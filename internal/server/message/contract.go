@@ -0,0 +1,28 @@
+package message
+
+import "encoding/json"
+
+// call of a constant contract method
+type ContractCall struct {
+	Proj string `json:"proj"`
+	// JSON array, or a string of comma separated values
+	Args json.RawMessage `json:"args"`
+	// node index, picked by the selector when absent
+	Node          *int `json:"node"`
+	BytesAsString bool `json:"bytes_as_string"`
+}
+
+// tx calling a contract method
+type ContractSend struct {
+	Proj string          `json:"proj"`
+	Args json.RawMessage `json:"args"`
+	Node *int            `json:"node"`
+	// keystore account, the default account when empty
+	From string `json:"from"`
+	// wei sent along, decimal
+	Value    string `json:"value"`
+	GasLimit uint64 `json:"gas_limit"`
+	Legacy   bool   `json:"legacy"`
+	// wait for the receipt before responding
+	Wait bool `json:"wait"`
+}
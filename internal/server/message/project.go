@@ -0,0 +1,13 @@
+package message
+
+type ProjectCreate struct {
+	Name string `json:"name" binding:"required"`
+	// 1 when absent
+	NodeCount int `json:"node_count"`
+	// first node's nodePort, 30545 when absent
+	Port int32 `json:"port"`
+}
+
+type ProjectScale struct {
+	NodeCount int `json:"node_count" binding:"required"`
+}
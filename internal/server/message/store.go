@@ -1,10 +0,0 @@
-package message
-
-type StoreQuery struct {
-	Key string `json:"key"`
-}
-
-type StoreWrite struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
-}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
+	"ethbaas/internal/model"
+	"ethbaas/pkg/contractclient"
+)
+
+type ContractSvc struct {
+	contractCli *contractclient.Client
+}
+
+func NewContractSvc(dbClient *db.Client) *ContractSvc {
+	s := &ContractSvc{
+		contractCli: contractclient.NewClient(dbClient),
+	}
+	return s
+}
+
+// active contracts, of all projects when proj is empty
+func (s *ContractSvc) List(proj string) (int64, []db.Contract, error) {
+	return s.contractCli.List(proj)
+}
+
+// call a constant method and decode its outputs
+func (s *ContractSvc) Call(ctx context.Context, proj, name, method, args string, node int, bytesAsString bool) (model.Fields, error) {
+	return s.contractCli.Query(ctx, proj, name, method, args, node, bytesAsString)
+}
+
+// send a tx calling method
+func (s *ContractSvc) Send(ctx context.Context, proj, name, method, args string, node int, txOpts model.TxOptions) (*model.TxResult, error) {
+	return s.contractCli.Write(ctx, proj, name, method, args, node, txOpts)
+}
+
+// stream decoded events of a contract to fn until ctx is done
+func (s *ContractSvc) Subscribe(ctx context.Context, proj, name string, q model.EventQuery, after string, fn func(*model.Event) error) error {
+	return s.contractCli.Subscribe(ctx, proj, name, q, after, ethcomm.AnyNode, fn)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// kinds of service errors, mapped to http status codes by the controller
+var (
+	ErrInvalid  = errors.New("invalid request")
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
+
+type svcError struct {
+	kind error
+	msg  string
+}
+
+func (e *svcError) Error() string {
+	return e.msg
+}
+
+func (e *svcError) Is(target error) bool {
+	return target == e.kind
+}
+
+func invalid(format string, a ...interface{}) error {
+	return &svcError{kind: ErrInvalid, msg: fmt.Sprintf(format, a...)}
+}
+
+func notFound(format string, a ...interface{}) error {
+	return &svcError{kind: ErrNotFound, msg: fmt.Sprintf(format, a...)}
+}
+
+func conflict(format string, a ...interface{}) error {
+	return &svcError{kind: ErrConflict, msg: fmt.Sprintf(format, a...)}
+}
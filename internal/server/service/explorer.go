@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"ethbaas/internal/db"
+	"ethbaas/internal/model"
+	"ethbaas/pkg/explorerclient"
+)
+
+type ExplorerSvc struct {
+	explorerCli *explorerclient.Client
+}
+
+func NewExplorerSvc(dbClient *db.Client) *ExplorerSvc {
+	s := &ExplorerSvc{
+		explorerCli: explorerclient.NewClient(dbClient),
+	}
+	return s
+}
+
+func (s *ExplorerSvc) Blocks(ctx context.Context, proj string, n int) ([]model.Block, error) {
+	return s.explorerCli.Blocks(ctx, proj, n)
+}
+
+func (s *ExplorerSvc) Block(ctx context.Context, proj, id string) (*model.Block, error) {
+	return s.explorerCli.Block(ctx, proj, id)
+}
+
+func (s *ExplorerSvc) Transaction(ctx context.Context, proj, hash string) (*model.Transaction, error) {
+	return s.explorerCli.Transaction(ctx, proj, hash)
+}
+
+func (s *ExplorerSvc) Receipt(ctx context.Context, proj, hash string) (*model.Receipt, error) {
+	return s.explorerCli.Receipt(ctx, proj, hash)
+}
+
+func (s *ExplorerSvc) Account(ctx context.Context, proj, address, block string) (*model.Account, error) {
+	return s.explorerCli.Account(ctx, proj, address, block)
+}
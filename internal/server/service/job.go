@@ -0,0 +1,119 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"ethbaas/internal/log"
+	"ethbaas/internal/model"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// finished jobs are kept this long for polling
+const jobRetention = time.Hour
+
+// JobManager runs project operations in background, one at a time per project.
+type JobManager struct {
+	mu   sync.Mutex
+	jobs map[string]*model.Job
+}
+
+func NewJobManager() *JobManager {
+	m := &JobManager{
+		jobs: map[string]*model.Job{},
+	}
+	return m
+}
+
+// ErrJobRunning is returned by Submit while the project has an unfinished job.
+type ErrJobRunning struct {
+	Job *model.Job
+}
+
+func (e *ErrJobRunning) Error() string {
+	return fmt.Sprintf("Project %s is busy with %s job %s", e.Job.Proj, e.Job.Kind, e.Job.ID)
+}
+
+func (e *ErrJobRunning) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// start fn as a job of kind on proj
+func (m *JobManager) Submit(kind, proj string, fn func() error) (*model.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	for _, job := range m.jobs {
+		if job.Proj == proj && !job.Done() {
+			cp := *job
+			return nil, &ErrJobRunning{Job: &cp}
+		}
+	}
+
+	now := time.Now().Unix()
+	job := &model.Job{
+		ID:      newJobID(),
+		Kind:    kind,
+		Proj:    proj,
+		Status:  model.JobPending,
+		Created: now,
+		Updated: now,
+	}
+	m.jobs[job.ID] = job
+	go m.run(job.ID, fn)
+	cp := *job
+	return &cp, nil
+}
+
+// copy of job id
+func (m *JobManager) Get(id string) (*model.Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	cp := *job
+	return &cp, true
+}
+
+func (m *JobManager) run(id string, fn func() error) {
+	m.update(id, model.JobRunning, nil)
+	err := fn()
+	if err != nil {
+		log.Logger.Warnf("Job %s failed: %s", id, err)
+		m.update(id, model.JobFailed, err)
+		return
+	}
+	m.update(id, model.JobSuccess, nil)
+}
+
+func (m *JobManager) update(id, status string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	job.Status = status
+	job.Updated = time.Now().Unix()
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// drop finished jobs past retention, called with mu held
+func (m *JobManager) prune() {
+	deadline := time.Now().Add(-jobRetention).Unix()
+	for id, job := range m.jobs {
+		if job.Done() && job.Updated < deadline {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/config"
+	"ethbaas/internal/db"
+	"ethbaas/internal/k8s"
+	"ethbaas/internal/model"
+	"ethbaas/pkg/chainclient"
+	"ethbaas/pkg/projclient"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// retry interval of peering nodes, added pods take a while to come up
+const peerRetryInterval = 5 * time.Second
+
+// project names end up in k8s namespaces, ethbaas-<name>
+var projNameRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,23}[a-z0-9])?$`)
+
+type ProjectSvc struct {
+	dbClient *db.Client
+	projCli  *projclient.Client
+	chainCli *chainclient.Client
+	jobs     *JobManager
+}
+
+func NewProjectSvc(dbClient *db.Client, jobs *JobManager) *ProjectSvc {
+	s := &ProjectSvc{
+		dbClient: dbClient,
+		projCli:  projclient.NewClient(dbClient),
+		chainCli: chainclient.NewClient(dbClient),
+		jobs:     jobs,
+	}
+	return s
+}
+
+func (s *ProjectSvc) List() (int64, []db.Project, error) {
+	return s.projCli.List()
+}
+
+func (s *ProjectSvc) Get(proj string) (*db.Project, error) {
+	if !s.dbClient.IsProjectExist(&db.Project{Name: proj}) {
+		return nil, notFound("Project %s not found", proj)
+	}
+	return s.projCli.Get(proj)
+}
+
+// init project yaml templates, nodes are started separately
+func (s *ProjectSvc) Create(p *model.Project) (*db.Project, error) {
+	if !projNameRe.MatchString(p.Name) {
+		return nil, invalid("Invalid project name %q, use at most 25 lowercase letters, digits or -", p.Name)
+	}
+	if p.NodeCount < 1 {
+		return nil, invalid("Node count must be at least 1")
+	}
+	last := p.FirstNodePort + int32(p.NodeCount) - 1
+	if p.FirstNodePort < projclient.MinNodePort || last > projclient.MaxNodePort {
+		return nil, invalid("Ports %d-%d not in %d-%d", p.FirstNodePort, last, projclient.MinNodePort, projclient.MaxNodePort)
+	}
+	if s.dbClient.IsProjectExist(&db.Project{Name: p.Name}) {
+		return nil, conflict("Project %s already exists", p.Name)
+	}
+	if err := s.projCli.Init(p); err != nil {
+		return nil, portError(err)
+	}
+	return s.projCli.Get(p.Name)
+}
+
+func (s *ProjectSvc) Start(proj string) (*model.Job, error) {
+	dbproj, err := s.Get(proj)
+	if err != nil {
+		return nil, err
+	}
+	if dbproj.Running {
+		return nil, conflict("Project %s is already running", proj)
+	}
+	return s.jobs.Submit("start", proj, func() error {
+		return s.projCli.Start(proj)
+	})
+}
+
+func (s *ProjectSvc) Stop(proj string) (*model.Job, error) {
+	dbproj, err := s.Get(proj)
+	if err != nil {
+		return nil, err
+	}
+	if !dbproj.Running {
+		return nil, conflict("Project %s is not running", proj)
+	}
+	return s.jobs.Submit("stop", proj, func() error {
+		return s.projCli.Stop(proj)
+	})
+}
+
+// delete a stopped project, running ones would leave their k8s resources behind
+func (s *ProjectSvc) Delete(proj string) (*model.Job, error) {
+	dbproj, err := s.Get(proj)
+	if err != nil {
+		return nil, err
+	}
+	if dbproj.Running {
+		return nil, conflict("Project %s is running, stop it first", proj)
+	}
+	return s.jobs.Submit("delete", proj, func() error {
+		return s.projCli.Delete(proj)
+	})
+}
+
+func (s *ProjectSvc) Scale(proj string, nodeCount int) (*model.Job, error) {
+	dbproj, err := s.Get(proj)
+	if err != nil {
+		return nil, err
+	}
+	if nodeCount < 1 {
+		return nil, invalid("Node count must be at least 1")
+	}
+	if nodeCount == dbproj.NodeCount {
+		return nil, conflict("Project %s already has %d nodes", proj, nodeCount)
+	}
+	return s.jobs.Submit("scale", proj, func() error {
+		if err := s.projCli.Scale(proj, nodeCount); err != nil {
+			return portError(err)
+		}
+		if !dbproj.Running || nodeCount < dbproj.NodeCount {
+			return nil
+		}
+		return s.peer(proj)
+	})
+}
+
+// connect the nodes of a running project once added nodes are up
+func (s *ProjectSvc) peer(proj string) error {
+	config.C.SetDefault("proj.peertimeout", "5m")
+	p, err := s.projCli.GetInModel(proj)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.C.GetDuration("proj.peertimeout"))
+	defer cancel()
+	ticker := time.NewTicker(peerRetryInterval)
+	defer ticker.Stop()
+	for {
+		err := s.chainCli.Cluster(ctx, p)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Peering nodes of %s: %w", proj, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// port errors of projclient as service errors
+func portError(err error) error {
+	switch {
+	case errors.Is(err, projclient.ErrPortRange):
+		return invalid("%s", err)
+	case errors.Is(err, projclient.ErrPortInUse):
+		return conflict("%s", err)
+	}
+	return err
+}
+
+func (s *ProjectSvc) Pods(ctx context.Context, proj string) ([]k8s.Pod, error) {
+	if _, err := s.Get(proj); err != nil {
+		return nil, err
+	}
+	p, err := s.projCli.GetInModel(proj)
+	if err != nil {
+		return nil, err
+	}
+	return s.chainCli.Pods(ctx, p)
+}
+
+func (s *ProjectSvc) Info(ctx context.Context, proj string) ([]chainclient.ChainInfo, error) {
+	if _, err := s.Get(proj); err != nil {
+		return nil, err
+	}
+	return s.chainCli.Info(ctx, proj)
+}
+
+func (s *ProjectSvc) Nodes(ctx context.Context, proj string) ([]model.NodeStatus, error) {
+	if _, err := s.Get(proj); err != nil {
+		return nil, err
+	}
+	return s.chainCli.Nodes(ctx, proj)
+}
+
+func (s *ProjectSvc) Job(id string) (*model.Job, error) {
+	job, ok := s.jobs.Get(id)
+	if !ok {
+		return nil, notFound("Job %s not found", id)
+	}
+	return job, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"ethbaas/internal/db"
+	"ethbaas/pkg/txclient"
+	"time"
+)
+
+type TxSvc struct {
+	txCli *txclient.Client
+}
+
+func NewTxSvc(dbClient *db.Client) *TxSvc {
+	s := &TxSvc{
+		txCli: txclient.NewClient(dbClient),
+	}
+	return s
+}
+
+func (s *TxSvc) List(proj, status string) (int64, []db.Transaction, error) {
+	return s.txCli.List(proj, status)
+}
+
+func (s *TxSvc) Get(hash string) (*db.Transaction, error) {
+	return s.txCli.Get(hash)
+}
+
+func (s *TxSvc) Resend(ctx context.Context, hash string) error {
+	return s.txCli.Resend(ctx, hash)
+}
+
+// resolve pending txs in background until ctx is done
+func (s *TxSvc) Run(ctx context.Context, interval time.Duration) {
+	s.txCli.Run(ctx, interval)
+}
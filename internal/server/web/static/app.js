@@ -0,0 +1,200 @@
+"use strict";
+
+const api = "/api/v1";
+
+// call the REST API and unwrap its {code, data, msg} response
+async function request(method, path, body) {
+  const opts = { method, headers: {} };
+  if (body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(body);
+  }
+  const resp = await fetch(api + path, opts);
+  const msg = await resp.json();
+  if (!resp.ok || msg.code !== 0) {
+    throw new Error(msg.msg || resp.statusText);
+  }
+  return msg.data;
+}
+
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+  Object.entries(attrs || {}).forEach(([k, v]) => {
+    if (k === "class") {
+      e.className = v;
+    } else if (k.startsWith("on")) {
+      e.addEventListener(k.slice(2), v);
+    } else {
+      e.setAttribute(k, v);
+    }
+  });
+  children.forEach((c) => e.append(c === undefined || c === null ? "" : c));
+  return e;
+}
+
+function fill(table, rows) {
+  const body = document.querySelector(`#${table} tbody`);
+  body.replaceChildren(...rows);
+}
+
+function time(unix) {
+  return new Date(unix * 1000).toLocaleString();
+}
+
+function status(text, error) {
+  const s = document.getElementById("status");
+  s.textContent = text;
+  s.className = error ? "error" : "";
+}
+
+function select(row) {
+  row.parentNode.querySelectorAll("tr").forEach((r) => r.classList.remove("selected"));
+  row.classList.add("selected");
+}
+
+async function loadProjects() {
+  try {
+    const list = await request("GET", "/projects");
+    fill("projects", list.map((p) => {
+      const row = el("tr", { class: "link" },
+        el("td", {}, p.Name),
+        el("td", {}, p.NodeCount),
+        el("td", { class: p.Running ? "running" : "stopped" }, p.Running ? "running" : "stopped"),
+        el("td", {}, time(p.Created)),
+      );
+      row.addEventListener("click", () => {
+        select(row);
+        showProject(p.Name);
+      });
+      return row;
+    }));
+    status("");
+  } catch (e) {
+    status(e.message, true);
+  }
+}
+
+async function showProject(name) {
+  document.querySelectorAll(".proj-name").forEach((e) => (e.textContent = name));
+  document.getElementById("project").hidden = false;
+  document.getElementById("contract").hidden = true;
+  fill("nodes", [el("tr", {}, el("td", { colspan: 7 }, "Loading..."))]);
+  fill("contracts", []);
+  await Promise.all([loadNodes(name), loadContracts(name)]);
+}
+
+async function loadNodes(proj) {
+  try {
+    const list = await request("GET", `/projects/${encodeURIComponent(proj)}/nodes`);
+    fill("nodes", list.map((n) => el("tr", {},
+      el("td", {}, `node${n.index}`),
+      el("td", {}, n.port),
+      el("td", { class: "mono" }, n.pod || "-"),
+      el("td", {}, n.podStatus || "-"),
+      el("td", {}, n.error ? "-" : n.blockNumber),
+      el("td", {}, n.error ? "-" : n.peers),
+      el("td", { class: "error" }, n.error || ""),
+    )));
+  } catch (e) {
+    fill("nodes", [el("tr", {}, el("td", { colspan: 7, class: "error" }, e.message))]);
+  }
+}
+
+async function loadContracts(proj) {
+  try {
+    const list = await request("GET", `/contracts?proj=${encodeURIComponent(proj)}`);
+    fill("contracts", list.map((c) => {
+      const row = el("tr", { class: "link" },
+        el("td", {}, c.Name),
+        el("td", {}, c.Version),
+        el("td", { class: "mono" }, c.Address),
+        el("td", {}, time(c.Created)),
+      );
+      row.addEventListener("click", () => {
+        select(row);
+        showContract(c);
+      });
+      return row;
+    }));
+  } catch (e) {
+    fill("contracts", [el("tr", {}, el("td", { colspan: 4, class: "error" }, e.message))]);
+  }
+}
+
+function signature(params) {
+  return (params || []).map((p) => `${p.type} ${p.name || ""}`.trim()).join(", ");
+}
+
+function showContract(contract) {
+  document.getElementById("contract-name").textContent = `${contract.Name} v${contract.Version}`;
+  document.getElementById("contract").hidden = false;
+
+  let abi = [];
+  try {
+    abi = JSON.parse(contract.ABI);
+  } catch (e) {
+    status(`Invalid ABI of ${contract.Name}: ${e.message}`, true);
+  }
+  const methods = abi.filter((m) => m.type === "function");
+  document.getElementById("methods").replaceChildren(...methods.map((m) => methodForm(contract, m)));
+}
+
+function txResult(tx) {
+  if (!tx.mined) {
+    return `tx ${tx.hash} pending`;
+  }
+  const lines = [`tx ${tx.hash}`, `block ${tx.blockNumber}, gas used ${tx.gasUsed}`];
+  if (tx.status === 0) {
+    lines.push(`reverted${tx.revertReason ? ": " + tx.revertReason : ""}`);
+  }
+  return lines.join("\n");
+}
+
+function methodForm(contract, method) {
+  const constant = method.stateMutability === "view" || method.stateMutability === "pure" || method.constant;
+  const payable = method.stateMutability === "payable" || method.payable;
+  const input = el("input", { type: "text", placeholder: signature(method.inputs) || "no arguments" });
+  const value = el("input", { type: "text", class: "value", placeholder: "value in wei" });
+  const output = el("pre", { hidden: "" });
+  const button = el("button", { type: "submit" }, constant ? "Call" : "Send");
+  if (!method.inputs || method.inputs.length === 0) {
+    input.disabled = true;
+  }
+
+  const form = el("form", {}, input, payable ? value : "", button);
+  form.addEventListener("submit", async (ev) => {
+    ev.preventDefault();
+    button.disabled = true;
+    output.hidden = false;
+    output.className = "";
+    output.textContent = "...";
+    const path = `/contracts/${encodeURIComponent(contract.Name)}/${constant ? "call" : "send"}/${encodeURIComponent(method.name)}`;
+    try {
+      const body = { proj: contract.Proj, args: input.value };
+      if (!constant) {
+        body.value = value.value.trim();
+        body.wait = true;
+      }
+      const data = await request("POST", path, body);
+      output.textContent = constant ? JSON.stringify(data, null, 2) : txResult(data);
+    } catch (e) {
+      output.className = "error";
+      output.textContent = e.message;
+    } finally {
+      button.disabled = false;
+    }
+  });
+
+  const returns = signature(method.outputs);
+  return el("div", { class: "method" },
+    el("div", { class: "sig mono" },
+      `${method.name}(${signature(method.inputs)})`,
+      returns ? ` returns (${returns})` : "",
+      el("span", { class: "kind" }, method.stateMutability || (constant ? "view" : "nonpayable")),
+    ),
+    form,
+    output,
+  );
+}
+
+loadProjects();
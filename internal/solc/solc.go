@@ -0,0 +1,141 @@
+package solc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"ethbaas/internal/config"
+	"ethbaas/internal/model"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/compiler"
+)
+
+// compiler settings, see solc in config.yaml
+type Options struct {
+	// solc binary
+	Path string
+	// expected compiler version, not checked when empty
+	Version  string
+	Optimize bool
+	Runs     int
+}
+
+func OptionsFromConfig() Options {
+	config.C.SetDefault("solc.path", "solc")
+	config.C.SetDefault("solc.runs", 200)
+	return Options{
+		Path:     config.C.GetString("solc.path"),
+		Version:  config.C.GetString("solc.version"),
+		Optimize: config.C.GetBool("solc.optimize"),
+		Runs:     config.C.GetInt("solc.runs"),
+	}
+}
+
+var versionRegexp = regexp.MustCompile(`([0-9]+)\.([0-9]+)\.([0-9]+)(\S*)`)
+
+// full version of the solc binary, e.g. 0.8.17+commit.8df45f5f.Linux.g++
+func Version(ctx context.Context, path string) (string, error) {
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("Running %s --version: %w", path, err)
+	}
+	v := versionRegexp.FindString(string(out))
+	if v == "" {
+		return "", fmt.Errorf("Cannot parse solc version from: %s", out)
+	}
+	return v, nil
+}
+
+// compile the contract name of source file path with solc; name defaults to
+// the file name when the source defines several contracts
+func CompileContract(ctx context.Context, opts Options, path, name string) (*model.Contract, error) {
+	version, err := Version(ctx, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Version != "" && !strings.HasPrefix(version, opts.Version) {
+		return nil, fmt.Errorf("solc %s found at %s, %s is configured", version, opts.Path, opts.Version)
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--combined-json", "abi,bin,bin-runtime"}
+	if opts.Optimize {
+		args = append(args, "--optimize", "--optimize-runs", strconv.Itoa(opts.Runs))
+	}
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, "--allow-paths", filepath.Dir(abspath), abspath)
+
+	cmd := exec.CommandContext(ctx, opts.Path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("solc failed: %w\n%s", err, stderr.String())
+	}
+
+	contracts, err := compiler.ParseCombinedJSON(stdout.Bytes(), string(source), languageVersion(version), version, strings.Join(args[:len(args)-1], " "))
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	compiled, err := findContract(contracts, name)
+	if err != nil {
+		return nil, err
+	}
+
+	abiJSON, err := json.Marshal(compiled.Info.AbiDefinition)
+	if err != nil {
+		return nil, err
+	}
+	contract := &model.Contract{
+		Name:     name,
+		ABI:      string(abiJSON),
+		BIN:      compiled.Code,
+		Source:   string(source),
+		Compiler: version,
+		Optimize: opts.Optimize,
+	}
+	if opts.Optimize {
+		contract.Runs = opts.Runs
+	}
+	return contract, nil
+}
+
+// compiled contracts are keyed by source path and contract name
+func findContract(contracts map[string]*compiler.Contract, name string) (*compiler.Contract, error) {
+	names := []string{}
+	for key, c := range contracts {
+		parts := strings.Split(key, ":")
+		cname := parts[len(parts)-1]
+		if cname == name {
+			return c, nil
+		}
+		names = append(names, cname)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("Contract %s not found in compiled contracts: %s", name, strings.Join(names, ", "))
+}
+
+func languageVersion(version string) string {
+	m := versionRegexp.FindStringSubmatch(version)
+	if m == nil {
+		return version
+	}
+	return m[1] + "." + m[2] + "." + m[3]
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"ethbaas/cmd"
 	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
 	"log"
 )
 
@@ -13,6 +14,7 @@ func main() {
 		log.Fatal(err)
 	}
 	defer dbClient.Close()
+	defer ethcomm.DefaultPool.Close()
 
 	if err := dbClient.Setup(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,65 @@
+package accountclient
+
+import (
+	"ethbaas/internal/ethcomm"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type Client struct {
+	ks *keystore.KeyStore
+}
+
+func NewClient() *Client {
+	c := &Client{
+		ks: ethcomm.OpenKeystore(),
+	}
+	return c
+}
+
+// create a new account protected by passphrase
+func (c *Client) New(passphrase string) (accounts.Account, error) {
+	return c.ks.NewAccount(passphrase)
+}
+
+// import hex private key file
+func (c *Client) ImportKey(path, passphrase string) (accounts.Account, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	hexKey := strings.TrimPrefix(strings.TrimSpace(string(b)), "0x")
+	privateKey, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("Invalid private key: %w", err)
+	}
+	return c.ks.ImportECDSA(privateKey, passphrase)
+}
+
+// import encrypted keystore json file, re-encrypted with newPassphrase
+func (c *Client) ImportKeyFile(path, passphrase, newPassphrase string) (accounts.Account, error) {
+	keyJson, err := ioutil.ReadFile(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	return c.ks.Import(keyJson, passphrase, newPassphrase)
+}
+
+// list keystore accounts
+func (c *Client) List() []accounts.Account {
+	return c.ks.Accounts()
+}
+
+// export account as keystore json encrypted with newPassphrase
+func (c *Client) Export(address, passphrase, newPassphrase string) ([]byte, error) {
+	acc, err := ethcomm.FindAccount(c.ks, address)
+	if err != nil {
+		return nil, err
+	}
+	return c.ks.Export(acc, passphrase, newPassphrase)
+}
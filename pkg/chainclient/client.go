@@ -2,59 +2,64 @@ package chainclient
 
 import (
 	"context"
+	"ethbaas/internal/config"
 	"ethbaas/internal/db"
 	"ethbaas/internal/ethcomm"
 	"ethbaas/internal/k8s"
+	"ethbaas/internal/log"
 	"ethbaas/internal/model"
 	"ethbaas/pkg/projclient"
 	"fmt"
 	"math/big"
 	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type Client struct {
 	db      *db.Client
 	projCli *projclient.Client
+	pool    *ethcomm.Pool
 }
 
 func NewClient(db *db.Client) *Client {
 	c := &Client{
 		db:      db,
 		projCli: projclient.NewClient(db),
+		pool:    ethcomm.DefaultPool,
 	}
 	return c
 }
 
-func (c *Client) Info(projName string) ([]ChainInfo, error) {
+func (c *Client) Info(ctx context.Context, projName string) ([]ChainInfo, error) {
 	dbproj, err := c.projCli.Get(projName)
 	if err != nil {
 		return nil, err
 	}
 
-	ports := dbproj.Str2Port()
 	infoes := []ChainInfo{}
 
-	for _, port := range ports {
-		conn, err := ethcomm.NewConn(port)
+	for _, node := range projclient.Nodes(dbproj) {
+		conn, err := c.pool.Get(ctx, node)
 		if err != nil {
 			return nil, err
 		}
-		defer conn.Close()
 
-		networkId, err := conn.EthCli.NetworkID(context.Background())
+		networkId, err := conn.EthCli.NetworkID(ctx)
 		if err != nil {
 			return nil, err
 		}
-		blockNum, err := conn.EthCli.BlockNumber(context.Background())
+		blockNum, err := conn.EthCli.BlockNumber(ctx)
 		if err != nil {
 			return nil, err
 		}
-		pc, err := conn.EthCli.PeerCount(context.Background())
+		pc, err := conn.EthCli.PeerCount(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		sp, err := conn.EthCli.SyncProgress(context.Background())
+		sp, err := conn.EthCli.SyncProgress(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -65,13 +70,13 @@ func (c *Client) Info(projName string) ([]ChainInfo, error) {
 			current = sp.CurrentBlock
 			highest = sp.HighestBlock
 		}
-		block, err := conn.EthCli.BlockByNumber(context.Background(), big.NewInt(int64(current)))
+		block, err := conn.EthCli.BlockByNumber(ctx, big.NewInt(int64(current)))
 		if err != nil {
 			return nil, err
 		}
 
-		conn.GethCli.GetNodeInfo(context.Background())
-		node, err := conn.GethCli.GetNodeInfo(context.Background())
+		conn.GethCli.GetNodeInfo(ctx)
+		node, err := conn.GethCli.GetNodeInfo(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -91,32 +96,123 @@ func (c *Client) Info(projName string) ([]ChainInfo, error) {
 	return infoes, nil
 }
 
-func (c *Client) Pods(p *model.Project) ([]k8s.Pod, error) {
-	return k8s.GetPods(p.NS())
+// indexer progress of projName, nil when the project was never indexed
+func (c *Client) IndexerState(projName string) (*db.IndexerState, error) {
+	return c.db.GetIndexerState(projName)
+}
+
+// follow new chain heads of a node through websocket, blocks until failure
+func (c *Client) WatchHeads(ctx context.Context, projName string, node int, fn func(*types.Header)) error {
+	dbproj, err := c.projCli.Get(projName)
+	if err != nil {
+		return err
+	}
+	nodes := projclient.Nodes(dbproj)
+	if node < 0 || node >= len(nodes) {
+		return fmt.Errorf("Project %s has no node%d", projName, node)
+	}
+
+	conn, err := c.pool.GetWS(ctx, nodes[node])
+	if err != nil {
+		return err
+	}
+
+	heads := make(chan *types.Header)
+	sub, err := conn.EthCli.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case head := <-heads:
+			fn(head)
+		}
+	}
+}
+
+// nodes of projName with pod state, block height and peers; unreachable nodes
+// are reported with their error instead of failing the call
+func (c *Client) Nodes(ctx context.Context, projName string) ([]model.NodeStatus, error) {
+	dbproj, err := c.projCli.Get(projName)
+	if err != nil {
+		return nil, err
+	}
+	p := &model.Project{Name: dbproj.Name}
+	pods, err := k8s.GetPods(ctx, p.NS())
+	if err != nil {
+		log.Logger.Warnf("Listing pods of %s failed: %s", projName, err)
+	}
+
+	config.C.SetDefault("rpc.checktimeout", "3s")
+	timeout := config.C.GetDuration("rpc.checktimeout")
+	list := []model.NodeStatus{}
+	for _, node := range projclient.Nodes(dbproj) {
+		status := model.NodeStatus{Index: node.Index, Port: node.Port, WsPort: node.WsPort}
+		for _, pod := range pods {
+			if hasPort(pod.NodePorts, node.Port) {
+				status.Pod, status.PodStatus = pod.Name, pod.Status
+			}
+		}
+		if err := c.nodeState(ctx, node, timeout, &status); err != nil {
+			status.Error = err.Error()
+		}
+		list = append(list, status)
+	}
+	return list, nil
+}
+
+func (c *Client) nodeState(ctx context.Context, node ethcomm.Node, timeout time.Duration, status *model.NodeStatus) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := c.pool.Get(ctx, node)
+	if err != nil {
+		return err
+	}
+	if status.BlockNumber, err = conn.EthCli.BlockNumber(ctx); err != nil {
+		return err
+	}
+	status.Peers, err = conn.EthCli.PeerCount(ctx)
+	return err
 }
 
-func (c *Client) Cluster(p *model.Project) error {
+func hasPort(ports []int32, port int32) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) Pods(ctx context.Context, p *model.Project) ([]k8s.Pod, error) {
+	return k8s.GetPods(ctx, p.NS())
+}
+
+func (c *Client) Cluster(ctx context.Context, p *model.Project) error {
 	dbproj, err := c.db.GetProject(p.Name)
 	if err != nil {
 		return err
 	}
-	ports := dbproj.Str2Port()
+	nodes := projclient.Nodes(dbproj)
 
-	node0Conn, err := ethcomm.NewConn(ports[0])
+	node0Conn, err := c.pool.Get(ctx, nodes[0])
 	if err != nil {
-		return nil
+		return err
 	}
-	defer node0Conn.Close()
 
-	for i := 1; i < len(ports); i++ {
-		apiPort := ports[i]
-		conn, err := ethcomm.NewConn(apiPort)
+	for i := 1; i < len(nodes); i++ {
+		conn, err := c.pool.Get(ctx, nodes[i])
 		if err != nil {
 			return err
 		}
-		defer conn.Close()
 
-		node, err := conn.GethCli.GetNodeInfo(context.Background())
+		node, err := conn.GethCli.GetNodeInfo(ctx)
 		if err != nil {
 			return err
 		}
@@ -125,7 +221,7 @@ func (c *Client) Cluster(p *model.Project) error {
 		enode = strings.ReplaceAll(enode, "127.0.0.1", fmt.Sprintf("node%d", i))
 		addResult := false
 
-		if err := node0Conn.RpcCli.Call(&addResult, "admin_addPeer", enode); err != nil {
+		if err := node0Conn.RpcCli.CallContext(ctx, &addResult, "admin_addPeer", enode); err != nil {
 			return err
 		}
 	}
@@ -1,14 +1,17 @@
 package contractclient
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"ethbaas/internal/db"
 	"ethbaas/internal/ethcomm"
 	"ethbaas/internal/model"
 	"ethbaas/pkg/projclient"
+	"ethbaas/pkg/txclient"
 	"fmt"
+	"math"
 	"math/big"
-	"reflect"
 	"strings"
 	"time"
 
@@ -22,83 +25,179 @@ import (
 type Client struct {
 	db      *db.Client
 	projCli *projclient.Client
+	sel     *ethcomm.Selector
+	nonces  *ethcomm.NonceManager
+	pool    *ethcomm.Pool
+	txCli   *txclient.Client
 }
 
 func NewClient(db *db.Client) *Client {
 	c := &Client{
 		db:      db,
 		projCli: projclient.NewClient(db),
+		sel:     ethcomm.DefaultSelector,
+		nonces:  ethcomm.DefaultNonceManager,
+		pool:    ethcomm.DefaultPool,
+		txCli:   txclient.NewClient(db),
 	}
 	return c
 }
 
-func (c *Client) Deploy(projName string, contract *model.Contract) (*common.Address, error) {
+// deploy contract with constructor args parsed against the abi, it is
+// registered only once the deployment is mined
+func (c *Client) Deploy(ctx context.Context, projName string, contract *model.Contract, args string, node int, txOpts model.TxOptions) (*model.TxResult, error) {
 	dbproj, err := c.db.GetProject(projName)
 	if err != nil {
 		return nil, err
 	}
 
-	apiPort := dbproj.Str2Port()[0]
+	contractAbi, err := abi.JSON(strings.NewReader(contract.ABI))
+	if err != nil {
+		return nil, err
+	}
+	constructor := contractAbi.Constructor
+	if txOpts.Value != nil && txOpts.Value.Sign() > 0 && !constructor.IsPayable() {
+		return nil, fmt.Errorf("Constructor of %s is not payable, cannot send value", contract.Name)
+	}
+	inputs, err := ethcomm.ParseArgs(constructor.Inputs, args)
+	if err != nil {
+		return nil, err
+	}
 
-	conn, err := ethcomm.NewConn(apiPort)
+	contractBin := common.FromHex(contract.BIN)
+	if len(contractBin) == 0 {
+		return nil, fmt.Errorf("Contract %s has no bytecode", contract.Name)
+	}
+	input, err := contractAbi.Pack("", inputs...)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
-	opts, err := ethcomm.GenTxOpts(conn)
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), node)
 	if err != nil {
 		return nil, err
 	}
 
-	contractAbi, err := abi.JSON(strings.NewReader(contract.ABI))
+	signer, err := ethcomm.TxSigner(txOpts)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := ethcomm.GenTxOpts(ctx, conn, signer, txOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	contractBin := common.FromHex(contract.BIN)
+	deployData := append(append([]byte{}, contractBin...), input...)
+	if err := ethcomm.SetGasLimit(ctx, conn, opts, txOpts.GasLimit, nil, deployData); err != nil {
+		return nil, err
+	}
 
-	version := "1.0"
-	address, tx, _, err := bind.DeployContract(opts, contractAbi, contractBin, conn.EthCli, version)
+	var address common.Address
+	var tx *types.Transaction
+	err = c.nonces.Send(ctx, dbproj.Name, conn, opts.From, func(nonce uint64) error {
+		opts.Nonce = new(big.Int).SetUint64(nonce)
+		address, tx, _, err = bind.DeployContract(opts, contractAbi, contractBin, conn.EthCli, inputs...)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("Contract deployed at:", address.Hex())
-	fmt.Println("Contract deploy tx:", tx.Hash().Hex())
-	err = c.db.AddContract(&db.Contract{
-		Name:    contract.Name,
-		Proj:    dbproj.Name,
-		Created: time.Now().Unix(),
-		Address: address.Hex(),
-		ABI:     contract.ABI,
-		BIN:     contract.BIN,
-	})
-
-	return &address, nil
-}
 
-func (c *Client) List() (int64, []db.Contract, error) {
-	return c.db.ListContract()
+	res, err := ethcomm.Outcome(ctx, conn, tx, txOpts.Wait)
+	res.Contract = address.Hex()
+	deploy := &db.PendingDeploy{
+		Name:     contract.Name,
+		Proj:     dbproj.Name,
+		Created:  time.Now().Unix(),
+		ABI:      contract.ABI,
+		BIN:      contract.BIN,
+		Source:   contract.Source,
+		Compiler: contract.Compiler,
+		Optimize: contract.Optimize,
+		Runs:     contract.Runs,
+	}
+	if rerr := c.txCli.RecordDeploy(deploy, args, tx, res); rerr != nil && err == nil {
+		err = rerr
+	}
+	return res, err
 }
 
-func (c *Client) Query(contractName, methodName, inputs string) (interface{}, error) {
-	contract, err := c.db.GetContract(contractName)
+// register a contract already deployed at address as a new version; when
+// contract has bytecode it must match the code on chain
+func (c *Client) Import(ctx context.Context, projName string, contract *model.Contract, address string, node int) (*db.Contract, error) {
+	dbproj, err := c.db.GetProject(projName)
 	if err != nil {
 		return nil, err
 	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("Invalid contract address: %s", address)
+	}
+	if _, err := abi.JSON(strings.NewReader(contract.ABI)); err != nil {
+		return nil, fmt.Errorf("Invalid abi: %w", err)
+	}
 
-	dbproj, err := c.db.GetProject(contract.Proj)
+	addr := common.HexToAddress(address)
+	var code []byte
+	err = c.sel.Read(ctx, projclient.Nodes(dbproj), node, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		code, err = conn.EthCli.CodeAt(ctx, addr, nil)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("No contract code at %s in project %s", addr.Hex(), dbproj.Name)
+	}
+	if contract.BIN != "" && !matchCode(code, common.FromHex(strings.TrimSpace(contract.BIN))) {
+		return nil, fmt.Errorf("Code at %s does not match the given bytecode", addr.Hex())
+	}
 
-	apiPort := dbproj.Str2Port()[0]
+	dbcontract := &db.Contract{
+		Name:     contract.Name,
+		Proj:     dbproj.Name,
+		Created:  time.Now().Unix(),
+		Address:  addr.Hex(),
+		ABI:      contract.ABI,
+		BIN:      contract.BIN,
+		Source:   contract.Source,
+		Compiler: contract.Compiler,
+		Optimize: contract.Optimize,
+		Runs:     contract.Runs,
+	}
+	if err := c.db.AddContract(dbcontract); err != nil {
+		return nil, err
+	}
+	return dbcontract, nil
+}
+
+// list active contracts, of all projects when projName is empty
+func (c *Client) List(projName string) (int64, []db.Contract, error) {
+	return c.db.ListContract(projName)
+}
 
-	conn, err := ethcomm.NewConn(apiPort)
+// deployed versions of a contract, newest first
+func (c *Client) Versions(projName, contractName string) ([]db.Contract, error) {
+	return c.db.ListContractVersions(projName, contractName)
+}
+
+// switch the active version of a contract
+func (c *Client) Use(projName, contractName string, version int) error {
+	return c.db.UseContractVersion(projName, contractName, version)
+}
+
+// call a constant method of the active contract version, projName may be
+// empty when the contract name is unique; outputs are decoded with the method
+// abi and fixed bytes rendered as text when bytesAsString is set
+func (c *Client) Query(ctx context.Context, projName, contractName, methodName, inputs string, node int, bytesAsString bool) (model.Fields, error) {
+	contract, err := c.db.GetContract(projName, contractName)
+	if err != nil {
+		return nil, err
+	}
+
+	dbproj, err := c.db.GetProject(contract.Proj)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
 	address := common.HexToAddress(contract.Address)
 
@@ -107,24 +206,13 @@ func (c *Client) Query(contractName, methodName, inputs string) (interface{}, er
 		return nil, err
 	}
 
-	inputList := []interface{}{}
 	method, ok := contractAbi.Methods[methodName]
 	if !ok {
-		return nil, fmt.Errorf("Method not found.")
-	}
-
-	userInputs := strings.Split(inputs, ",")
-	for i := 0; i < len(method.Inputs); i++ {
-		methodInput := method.Inputs[i]
-		userInput := userInputs[i]
-		switch methodInput.Type.GetType() {
-		case reflect.TypeOf([32]uint8{}):
-			transInput := [32]uint8{}
-			copy(transInput[:], []byte(userInput))
-			inputList = append(inputList, transInput)
-		default:
-			inputList = append(inputList, userInput)
-		}
+		return nil, fmt.Errorf("Method %s not found.", methodName)
+	}
+	inputList, err := ethcomm.ParseArgs(method.Inputs, inputs)
+	if err != nil {
+		return nil, err
 	}
 
 	data, err := contractAbi.Pack(methodName, inputList...)
@@ -136,92 +224,295 @@ func (c *Client) Query(contractName, methodName, inputs string) (interface{}, er
 		To:   &address,
 		Data: data,
 	}
-	return conn.EthCli.CallContract(context.Background(), msg, nil)
+	var res []byte
+	err = c.sel.Read(ctx, projclient.Nodes(dbproj), node, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		res, err = conn.EthCli.CallContract(ctx, msg, nil)
+		return err
+	})
+	if err != nil {
+		if reason, ok := ethcomm.RevertReason(err); ok {
+			return nil, fmt.Errorf("Call %s reverted: %s", methodName, reason)
+		}
+		return nil, err
+	}
+	return ethcomm.DecodeOutputs(method.Outputs, res, bytesAsString)
 }
 
-func (c *Client) Write(contractName, methodName, inputs string) (string, error) {
-	contract, err := c.db.GetContract(contractName)
+func (c *Client) Write(ctx context.Context, projName, contractName, methodName, inputs string, node int, txOpts model.TxOptions) (*model.TxResult, error) {
+	contract, err := c.db.GetContract(projName, contractName)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	dbproj, err := c.db.GetProject(contract.Proj)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	apiPort := dbproj.Str2Port()[0]
-
-	conn, err := ethcomm.NewConn(apiPort)
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), node)
 	if err != nil {
-		return "", nil
+		return nil, err
 	}
-	defer conn.Close()
 
 	address := common.HexToAddress(contract.Address)
 
 	contractAbi, err := abi.JSON(strings.NewReader(contract.ABI))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	inputList := []interface{}{}
 	method, ok := contractAbi.Methods[methodName]
 	if !ok {
-		return "", fmt.Errorf("Method not found.")
-	}
-
-	userInputs := strings.Split(inputs, ",")
-	for i := 0; i < len(method.Inputs); i++ {
-		methodInput := method.Inputs[i]
-		userInput := userInputs[i]
-		switch methodInput.Type.GetType() {
-		case reflect.TypeOf([32]uint8{}):
-			transInput := [32]uint8{}
-			copy(transInput[:], []byte(userInput))
-			inputList = append(inputList, transInput)
-		default:
-			inputList = append(inputList, userInput)
-		}
+		return nil, fmt.Errorf("Method %s not found.", methodName)
+	}
+	inputList, err := ethcomm.ParseArgs(method.Inputs, inputs)
+	if err != nil {
+		return nil, err
 	}
 
 	data, err := contractAbi.Pack(methodName, inputList...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	txOpt, err := ethcomm.GenTxOpts(conn)
+	signer, err := ethcomm.TxSigner(txOpts)
+	if err != nil {
+		return nil, err
+	}
+	txOpt, err := ethcomm.GenTxOpts(ctx, conn, signer, txOpts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	tx := types.NewTransaction(
-		txOpt.Nonce.Uint64(),
-		address,
-		big.NewInt(0),
-		txOpt.GasLimit,
-		txOpt.GasPrice,
-		data,
-	)
-	chainId, err := conn.EthCli.ChainID(context.Background())
+	chainId, err := conn.ChainID(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if err := ethcomm.SetGasLimit(ctx, conn, txOpt, txOpts.GasLimit, &address, data); err != nil {
+		return nil, err
 	}
 
-	privateKey, err := ethcomm.GetAdminPk()
+	var signTx *types.Transaction
+	err = c.nonces.Send(ctx, dbproj.Name, conn, txOpt.From, func(nonce uint64) error {
+		tx := ethcomm.NewTx(txOpt, chainId, nonce, &address, data)
+		signTx, err = txOpt.Signer(txOpt.From, tx)
+		if err != nil {
+			return err
+		}
+		return conn.EthCli.SendTransaction(ctx, signTx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ethcomm.Outcome(ctx, conn, signTx, txOpts.Wait)
+	c.txCli.Track(dbproj.Name, contract.Name, methodName, inputs, signTx, res)
+	return res, err
+}
+
+// runtime code matches bin, either runtime or creation bytecode, ignoring
+// the solc metadata which differs between otherwise identical builds
+func matchCode(runtime, bin []byte) bool {
+	runtime = stripMetadata(runtime)
+	if len(runtime) == 0 {
+		return false
+	}
+	if bytes.Equal(runtime, stripMetadata(bin)) {
+		return true
+	}
+	// creation code embeds the runtime code after the constructor
+	return bytes.Contains(bin, runtime)
+}
+
+// code without the trailing cbor metadata, whose length is in the last two bytes
+func stripMetadata(code []byte) []byte {
+	if len(code) < 2 {
+		return code
+	}
+	n := int(binary.BigEndian.Uint16(code[len(code)-2:]))
+	if n+2 > len(code) || n == 0 {
+		return code
+	}
+	// cbor metadata is a map, major type 5
+	if code[len(code)-2-n]>>5 != 5 {
+		return code
+	}
+	return code[:len(code)-2-n]
+}
+
+// logs of the active contract version decoded with its abi
+func (c *Client) Events(ctx context.Context, projName, contractName string, q model.EventQuery, node int) ([]*model.Event, error) {
+	nodes, contractAbi, query, err := c.eventQuery(projName, contractName, q)
+	if err != nil {
+		return nil, err
+	}
+	query.FromBlock, query.ToBlock = q.FromBlock, q.ToBlock
+
+	var logs []types.Log
+	err = c.sel.Read(ctx, nodes, node, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		logs, err = conn.EthCli.FilterLogs(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeLogs(contractAbi, logs, q.BytesAsString)
+}
+
+// stream events of the active contract version to fn until ctx is done or
+// the subscription fails. logs from q.FromBlock are backfilled before live
+// ones; events up to and including the after id are skipped when set
+func (c *Client) Subscribe(ctx context.Context, projName, contractName string, q model.EventQuery, after string, node int, fn func(*model.Event) error) error {
+	nodes, contractAbi, query, err := c.eventQuery(projName, contractName, q)
+	if err != nil {
+		return err
+	}
+
+	var last *model.Event
+	if after != "" {
+		block, index, err := model.ParseEventID(after)
+		if err != nil {
+			return err
+		}
+		last = &model.Event{BlockNumber: block, LogIndex: index}
+		if q.FromBlock == nil || q.FromBlock.Uint64() < block {
+			q.FromBlock = new(big.Int).SetUint64(block)
+		}
+	}
+
+	picked, _, err := c.sel.Pick(ctx, nodes, node)
+	if err != nil {
+		return err
+	}
+	conn, err := c.pool.GetWS(ctx, picked)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	// subscribe before backfilling so no log falls in between
+	live := make(chan types.Log, 128)
+	sub, err := conn.EthCli.SubscribeFilterLogs(ctx, query, live)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	send := func(log types.Log) error {
+		// logs removed by reorgs are passed on to undo delivered ones, and
+		// last moves back so that the logs replacing them are delivered
+		if log.Removed {
+			if last != nil && !logAfter(log, last) {
+				last = eventBefore(log)
+			}
+		} else if last != nil && !logAfter(log, last) {
+			return nil
+		}
+		event, ok, err := ethcomm.DecodeLog(&contractAbi, log, q.BytesAsString)
+		if err != nil || !ok {
+			return err
+		}
+		if !log.Removed {
+			last = event
+		}
+		return fn(event)
+	}
+
+	if q.FromBlock != nil {
+		head, err := conn.EthCli.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		if q.FromBlock.Uint64() <= head {
+			query.FromBlock, query.ToBlock = q.FromBlock, new(big.Int).SetUint64(head)
+			logs, err := conn.EthCli.FilterLogs(ctx, query)
+			if err != nil {
+				return err
+			}
+			for _, log := range logs {
+				if err := send(log); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
-	signTx, err := types.SignTx(tx, types.NewEIP155Signer(chainId), privateKey)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case log := <-live:
+			if err := send(log); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nodes, abi and address and topic filter of events of the active contract version
+func (c *Client) eventQuery(projName, contractName string, q model.EventQuery) ([]ethcomm.Node, abi.ABI, ethereum.FilterQuery, error) {
+	query := ethereum.FilterQuery{}
+	contract, err := c.db.GetContract(projName, contractName)
 	if err != nil {
-		return "", err
+		return nil, abi.ABI{}, query, err
 	}
 
-	err = conn.EthCli.SendTransaction(context.Background(), signTx)
+	dbproj, err := c.db.GetProject(contract.Proj)
 	if err != nil {
-		return "", err
+		return nil, abi.ABI{}, query, err
 	}
 
-	return signTx.Hash().Hex(), nil
+	contractAbi, err := abi.JSON(strings.NewReader(contract.ABI))
+	if err != nil {
+		return nil, abi.ABI{}, query, err
+	}
+
+	query.Addresses = []common.Address{common.HexToAddress(contract.Address)}
+	if q.Event != "" {
+		event, ok := contractAbi.Events[q.Event]
+		if !ok {
+			return nil, abi.ABI{}, query, fmt.Errorf("Event %s not found.", q.Event)
+		}
+		query.Topics, err = ethcomm.EventTopics(event, q.Filters)
+		if err != nil {
+			return nil, abi.ABI{}, query, err
+		}
+	} else if len(q.Filters) > 0 {
+		return nil, abi.ABI{}, query, fmt.Errorf("Filters require an event")
+	}
+	return projclient.Nodes(dbproj), contractAbi, query, nil
+}
+
+func decodeLogs(contractAbi abi.ABI, logs []types.Log, bytesAsString bool) ([]*model.Event, error) {
+	events := []*model.Event{}
+	for _, log := range logs {
+		event, ok, err := ethcomm.DecodeLog(&contractAbi, log, bytesAsString)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// position just before log in chain order, nil before the first log
+func eventBefore(log types.Log) *model.Event {
+	switch {
+	case log.Index > 0:
+		return &model.Event{BlockNumber: log.BlockNumber, LogIndex: log.Index - 1}
+	case log.BlockNumber > 0:
+		return &model.Event{BlockNumber: log.BlockNumber - 1, LogIndex: math.MaxUint}
+	}
+	return nil
+}
+
+// log comes after event in chain order
+func logAfter(log types.Log, event *model.Event) bool {
+	if log.BlockNumber != event.BlockNumber {
+		return log.BlockNumber > event.BlockNumber
+	}
+	return log.Index > event.LogIndex
 }
@@ -0,0 +1,102 @@
+package contractclient
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// solc style cbor metadata, a map of the ipfs hash and compiler version
+// followed by its length
+func testMetadata(hash byte) []byte {
+	meta := []byte{0xa2, 0x64}
+	meta = append(meta, "ipfs"...)
+	meta = append(meta, 0x58, 0x22)
+	meta = append(meta, bytes.Repeat([]byte{hash}, 34)...)
+	meta = append(meta, 0x64)
+	meta = append(meta, "solc"...)
+	meta = append(meta, 0x43, 0x00, 0x08, 0x11)
+	return append(meta, 0x00, byte(len(meta)))
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestStripMetadata(t *testing.T) {
+	code := []byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}
+	tests := []struct {
+		name string
+		code []byte
+		want []byte
+	}{
+		{"with metadata", join(code, testMetadata(1)), code},
+		{"only metadata", testMetadata(1), []byte{}},
+		{"without metadata", code, code},
+		{"length past start", []byte{0xa2, 0x00, 0x10}, []byte{0xa2, 0x00, 0x10}},
+		{"zero length", []byte{0x60, 0x00, 0x00}, []byte{0x60, 0x00, 0x00}},
+		{"not a map", join(code, []byte{0x60, 0x01, 0x00, 0x02}), join(code, []byte{0x60, 0x01, 0x00, 0x02})},
+		{"short", []byte{0x01}, []byte{0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripMetadata(tt.code); !bytes.Equal(got, tt.want) {
+				t.Errorf("got %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchCode(t *testing.T) {
+	runtime := []byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x34, 0x80, 0x15}
+	constructor := []byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x61, 0x01, 0x00, 0x80, 0xf3, 0xfe}
+	args := bytes.Repeat([]byte{0x00}, 32)
+	tests := []struct {
+		name    string
+		runtime []byte
+		bin     []byte
+		want    bool
+	}{
+		{"runtime bin", join(runtime, testMetadata(1)), join(runtime, testMetadata(1)), true},
+		{"runtime bin other metadata", join(runtime, testMetadata(1)), join(runtime, testMetadata(2)), true},
+		{"creation bin", join(runtime, testMetadata(1)), join(constructor, runtime, testMetadata(2)), true},
+		{"creation bin with args", join(runtime, testMetadata(1)), join(constructor, runtime, testMetadata(2), args), true},
+		{"runtime without metadata", runtime, join(constructor, runtime), true},
+		{"other code", join([]byte{0x60, 0x01, 0x60, 0x02, 0x01}, testMetadata(1)), join(constructor, runtime, testMetadata(1)), false},
+		{"no code", nil, join(runtime, testMetadata(1)), false},
+		{"only metadata", testMetadata(1), join(runtime, testMetadata(1)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchCode(tt.runtime, tt.bin); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventBefore(t *testing.T) {
+	logs := []types.Log{
+		{BlockNumber: 10, Index: 3},
+		{BlockNumber: 10, Index: 0},
+		{BlockNumber: 11, Index: 0},
+	}
+	for _, log := range logs {
+		before := eventBefore(log)
+		if !logAfter(log, before) {
+			t.Errorf("log %d:%d not after %s", log.BlockNumber, log.Index, before.ID())
+		}
+		// the last log of the previous block and the previous log of the block stay delivered
+		prev := types.Log{BlockNumber: log.BlockNumber - 1, Index: 50}
+		if log.Index > 0 {
+			prev = types.Log{BlockNumber: log.BlockNumber, Index: log.Index - 1}
+		}
+		if logAfter(prev, before) {
+			t.Errorf("log %d:%d after %s", prev.BlockNumber, prev.Index, before.ID())
+		}
+	}
+	if eventBefore(types.Log{}) != nil {
+		t.Error("position before the first log of the chain")
+	}
+}
@@ -1,73 +1,104 @@
 package contractclient
 
 import (
+	"context"
 	"ethbaas/contract/store"
 	"ethbaas/internal/db"
 	"ethbaas/internal/ethcomm"
+	"ethbaas/internal/model"
 	"ethbaas/pkg/projclient"
-	"fmt"
+	"ethbaas/pkg/txclient"
+	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type StoreClient struct {
 	db      *db.Client
 	projCli *projclient.Client
+	sel     *ethcomm.Selector
+	nonces  *ethcomm.NonceManager
+	txCli   *txclient.Client
 }
 
 func NewStoreClient(db *db.Client) *StoreClient {
 	c := &StoreClient{
 		db:      db,
 		projCli: projclient.NewClient(db),
+		sel:     ethcomm.DefaultSelector,
+		nonces:  ethcomm.DefaultNonceManager,
+		txCli:   txclient.NewClient(db),
 	}
 	return c
 }
 
-// deploy kv store contract
-func (c *StoreClient) Deploy(projName, contractName, contractPath string) error {
+// deploy kv store contract, it is registered only once the deployment is mined
+func (c *StoreClient) Deploy(ctx context.Context, projName, contractName, contractPath string, node int, txOpts model.TxOptions) (*model.TxResult, error) {
 	dbproj, err := c.projCli.Get(projName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	apiPort := dbproj.Str2Port()[0]
-
-	conn, err := ethcomm.NewConn(apiPort)
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), node)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer conn.Close()
 
-	auth, err := ethcomm.GenTxOpts(conn)
+	signer, err := ethcomm.TxSigner(txOpts)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	auth, err := ethcomm.GenTxOpts(ctx, conn, signer, txOpts)
+	if err != nil {
+		return nil, err
 	}
 
 	input := "1.0"
-	address, tx, instance, err := store.DeployStore(auth, conn.EthCli, input)
+	storeAbi, err := store.StoreMetaData.GetAbi()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	packed, err := storeAbi.Pack("", input)
+	if err != nil {
+		return nil, err
+	}
+	deployData := append(common.FromHex(store.StoreMetaData.Bin), packed...)
+	if err := ethcomm.SetGasLimit(ctx, conn, auth, txOpts.GasLimit, nil, deployData); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("Contract deployed at:", address.Hex())
-	fmt.Println("Contract deploy tx:", tx.Hash().Hex())
-	_ = instance
+	var address common.Address
+	var tx *types.Transaction
+	err = c.nonces.Send(ctx, dbproj.Name, conn, auth.From, func(nonce uint64) error {
+		auth.Nonce = new(big.Int).SetUint64(nonce)
+		address, tx, _, err = store.DeployStore(auth, conn.EthCli, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	err = c.db.AddContract(&db.Contract{
+	res, err := ethcomm.Outcome(ctx, conn, tx, txOpts.Wait)
+	res.Contract = address.Hex()
+	deploy := &db.PendingDeploy{
 		Name:    contractName,
 		Proj:    dbproj.Name,
 		Created: time.Now().Unix(),
-		Address: address.Hex(),
 		ABI:     store.StoreABI,
 		BIN:     store.StoreBin,
-	})
-	return err
+	}
+	if rerr := c.txCli.RecordDeploy(deploy, input, tx, res); rerr != nil && err == nil {
+		err = rerr
+	}
+	return res, err
 }
 
 // query kv store contract
-func (c *StoreClient) Query(contractName, key string) (interface{}, error) {
-	contract, err := c.db.GetContract(contractName)
+func (c *StoreClient) Query(ctx context.Context, projName, contractName, key string, node int) (interface{}, error) {
+	contract, err := c.db.GetContract(projName, contractName)
 	if err != nil {
 		return nil, err
 	}
@@ -77,23 +108,19 @@ func (c *StoreClient) Query(contractName, key string) (interface{}, error) {
 		return nil, err
 	}
 
-	apiPort := dbproj.Str2Port()[0]
-
-	conn, err := ethcomm.NewConn(apiPort)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-
 	address := common.HexToAddress(contract.Address)
-	instance, err := store.NewStore(address, conn.EthCli)
-	if err != nil {
-		return nil, err
-	}
-
 	bkey := [32]byte{}
 	copy(bkey[:], []byte(key))
-	value, err := instance.Items(nil, bkey)
+
+	var value [32]byte
+	err = c.sel.Read(ctx, projclient.Nodes(dbproj), node, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		instance, err := store.NewStore(address, conn.EthCli)
+		if err != nil {
+			return err
+		}
+		value, err = instance.Items(&bind.CallOpts{Context: ctx}, bkey)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -101,42 +128,67 @@ func (c *StoreClient) Query(contractName, key string) (interface{}, error) {
 }
 
 // write to kv store contract
-func (c *StoreClient) Write(contractName, key, value string) (string, error) {
-	contract, err := c.db.GetContract(contractName)
+func (c *StoreClient) Write(ctx context.Context, projName, contractName, key, value string, node int, txOpts model.TxOptions) (*model.TxResult, error) {
+	contract, err := c.db.GetContract(projName, contractName)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	dbproj, err := c.db.GetProject(contract.Proj)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	apiPort := dbproj.Str2Port()[0]
-
-	conn, err := ethcomm.NewConn(apiPort)
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), node)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer conn.Close()
 
 	address := common.HexToAddress(contract.Address)
 	instance, err := store.NewStore(address, conn.EthCli)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	signer, err := ethcomm.TxSigner(txOpts)
+	if err != nil {
+		return nil, err
 	}
-	auth, err := ethcomm.GenTxOpts(conn)
+	auth, err := ethcomm.GenTxOpts(ctx, conn, signer, txOpts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	bkey := [32]byte{}
 	bvalue := [32]byte{}
 	copy(bkey[:], []byte(key))
 	copy(bvalue[:], []byte(value))
-	tx, err := instance.SetItem(auth, bkey, bvalue)
+	if err := setItemGas(ctx, conn, auth, txOpts.GasLimit, address, bkey, bvalue); err != nil {
+		return nil, err
+	}
+
+	var tx *types.Transaction
+	err = c.nonces.Send(ctx, dbproj.Name, conn, auth.From, func(nonce uint64) error {
+		auth.Nonce = new(big.Int).SetUint64(nonce)
+		tx, err = instance.SetItem(auth, bkey, bvalue)
+		return err
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	res, err := ethcomm.Outcome(ctx, conn, tx, txOpts.Wait)
+	c.txCli.Track(dbproj.Name, contractName, "setItem", key+","+value, tx, res)
+	return res, err
+}
+
+// set gas limit of a setItem call
+func setItemGas(ctx context.Context, conn *ethcomm.ChainConn, auth *bind.TransactOpts, gasLimit uint64, address common.Address, key, value [32]byte) error {
+	storeAbi, err := store.StoreMetaData.GetAbi()
+	if err != nil {
+		return err
+	}
+	data, err := storeAbi.Pack("setItem", key, value)
+	if err != nil {
+		return err
 	}
-	return tx.Hash().Hex(), nil
+	return ethcomm.SetGasLimit(ctx, conn, auth, gasLimit, &address, data)
 }
@@ -0,0 +1,302 @@
+package explorerclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
+	"ethbaas/internal/model"
+	"ethbaas/pkg/projclient"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// most blocks returned by one Blocks call
+const MaxBlocks = 100
+
+type Client struct {
+	db  *db.Client
+	sel *ethcomm.Selector
+}
+
+func NewClient(db *db.Client) *Client {
+	c := &Client{
+		db:  db,
+		sel: ethcomm.DefaultSelector,
+	}
+	return c
+}
+
+// latest n blocks, newest first
+func (c *Client) Blocks(ctx context.Context, projName string, n int) ([]model.Block, error) {
+	if n <= 0 || n > MaxBlocks {
+		return nil, fmt.Errorf("Block count must be between 1 and %d", MaxBlocks)
+	}
+	list := []model.Block{}
+	err := c.read(ctx, projName, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		head, err := conn.EthCli.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		list = list[:0]
+		for i := 0; i < n && uint64(i) <= head; i++ {
+			block, err := conn.EthCli.BlockByNumber(ctx, new(big.Int).SetUint64(head-uint64(i)))
+			if err != nil {
+				return err
+			}
+			list = append(list, *newBlock(block))
+		}
+		return nil
+	})
+	return list, err
+}
+
+// block by decimal or hex number, hash, or latest
+func (c *Client) Block(ctx context.Context, projName, id string) (*model.Block, error) {
+	var block *types.Block
+	err := c.read(ctx, projName, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		var err error
+		if len(id) == 66 && strings.HasPrefix(id, "0x") {
+			block, err = conn.EthCli.BlockByHash(ctx, common.HexToHash(id))
+			return err
+		}
+		number, err := parseBlockNumber(id)
+		if err != nil {
+			return err
+		}
+		block, err = conn.EthCli.BlockByNumber(ctx, number)
+		return err
+	})
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("Block %s not found in project %s", id, projName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newBlock(block), nil
+}
+
+// transaction by hash, pending ones included
+func (c *Client) Transaction(ctx context.Context, projName, hash string) (*model.Transaction, error) {
+	if !isHash(hash) {
+		return nil, fmt.Errorf("Invalid tx hash: %s", hash)
+	}
+	var tx *rpcTransaction
+	err := c.read(ctx, projName, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		return conn.RpcCli.CallContext(ctx, &tx, "eth_getTransactionByHash", common.HexToHash(hash))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("Transaction %s not found in project %s", hash, projName)
+	}
+	return tx.model(), nil
+}
+
+// receipt of a mined transaction
+func (c *Client) Receipt(ctx context.Context, projName, hash string) (*model.Receipt, error) {
+	if !isHash(hash) {
+		return nil, fmt.Errorf("Invalid tx hash: %s", hash)
+	}
+	var receipt *types.Receipt
+	err := c.read(ctx, projName, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		var err error
+		receipt, err = conn.EthCli.TransactionReceipt(ctx, common.HexToHash(hash))
+		return err
+	})
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("Receipt of %s not found in project %s, tx is pending or unknown", hash, projName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newReceipt(receipt), nil
+}
+
+// balance, nonce and code of address at block, latest when block is empty
+func (c *Client) Account(ctx context.Context, projName, address, block string) (*model.Account, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("Invalid address: %s", address)
+	}
+	number, err := parseBlockNumber(block)
+	if err != nil {
+		return nil, err
+	}
+	addr := common.HexToAddress(address)
+	account := &model.Account{Address: addr.Hex()}
+	err = c.read(ctx, projName, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		// pin latest to a number so all fields come from the same block
+		at := number
+		if at == nil {
+			head, err := conn.EthCli.BlockNumber(ctx)
+			if err != nil {
+				return err
+			}
+			at = new(big.Int).SetUint64(head)
+		}
+		balance, err := conn.EthCli.BalanceAt(ctx, addr, at)
+		if err != nil {
+			return err
+		}
+		nonce, err := conn.EthCli.NonceAt(ctx, addr, at)
+		if err != nil {
+			return err
+		}
+		code, err := conn.EthCli.CodeAt(ctx, addr, at)
+		if err != nil {
+			return err
+		}
+		account.BlockNumber = at.Uint64()
+		account.Balance = balance.String()
+		account.Nonce = nonce
+		account.Contract = len(code) > 0
+		account.Code = hexutil.Encode(code)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// run fn on a node of the project
+func (c *Client) read(ctx context.Context, projName string, fn func(context.Context, *ethcomm.ChainConn) error) error {
+	dbproj, err := c.db.GetProject(projName)
+	if err != nil {
+		return err
+	}
+	return c.sel.Read(ctx, projclient.Nodes(dbproj), ethcomm.AnyNode, fn)
+}
+
+// block number from decimal or 0x hex, nil for latest or empty
+func parseBlockNumber(s string) (*big.Int, error) {
+	if s == "" || s == "latest" {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid block number: %s", s)
+	}
+	return new(big.Int).SetUint64(n), nil
+}
+
+func isHash(s string) bool {
+	b, err := hexutil.Decode(s)
+	return err == nil && len(b) == common.HashLength
+}
+
+func newBlock(block *types.Block) *model.Block {
+	b := &model.Block{
+		Number:       block.NumberU64(),
+		Hash:         block.Hash().Hex(),
+		ParentHash:   block.ParentHash().Hex(),
+		Time:         block.Time(),
+		Miner:        block.Coinbase().Hex(),
+		Difficulty:   block.Difficulty().String(),
+		GasUsed:      block.GasUsed(),
+		GasLimit:     block.GasLimit(),
+		Size:         uint64(block.Size()),
+		Transactions: make([]string, len(block.Transactions())),
+	}
+	if block.BaseFee() != nil {
+		b.BaseFee = block.BaseFee().String()
+	}
+	for i, tx := range block.Transactions() {
+		b.Transactions[i] = tx.Hash().Hex()
+	}
+	return b
+}
+
+func newReceipt(receipt *types.Receipt) *model.Receipt {
+	r := &model.Receipt{
+		TxHash:            receipt.TxHash.Hex(),
+		Status:            receipt.Status,
+		BlockNumber:       receipt.BlockNumber.Uint64(),
+		BlockHash:         receipt.BlockHash.Hex(),
+		TxIndex:           receipt.TransactionIndex,
+		GasUsed:           receipt.GasUsed,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		Logs:              make([]model.Log, len(receipt.Logs)),
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		r.ContractAddress = receipt.ContractAddress.Hex()
+	}
+	for i, l := range receipt.Logs {
+		topics := make([]string, len(l.Topics))
+		for j, t := range l.Topics {
+			topics[j] = t.Hex()
+		}
+		r.Logs[i] = model.Log{
+			Address:  l.Address.Hex(),
+			Topics:   topics,
+			Data:     hexutil.Encode(l.Data),
+			LogIndex: l.Index,
+			Removed:  l.Removed,
+		}
+	}
+	return r
+}
+
+// eth_getTransactionByHash result, the tx with the fields ethclient drops
+type rpcTransaction struct {
+	tx *types.Transaction
+	rpcTxExtra
+}
+
+type rpcTxExtra struct {
+	BlockNumber *hexutil.Big    `json:"blockNumber"`
+	BlockHash   *common.Hash    `json:"blockHash"`
+	From        common.Address  `json:"from"`
+	TxIndex     *hexutil.Uint64 `json:"transactionIndex"`
+}
+
+func (t *rpcTransaction) UnmarshalJSON(msg []byte) error {
+	if err := json.Unmarshal(msg, &t.tx); err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, &t.rpcTxExtra)
+}
+
+func (t *rpcTransaction) model() *model.Transaction {
+	tx := &model.Transaction{
+		Hash:     t.tx.Hash().Hex(),
+		Type:     t.tx.Type(),
+		From:     t.From.Hex(),
+		Value:    t.tx.Value().String(),
+		Nonce:    t.tx.Nonce(),
+		Gas:      t.tx.Gas(),
+		GasPrice: t.tx.GasPrice().String(),
+		Input:    hexutil.Encode(t.tx.Data()),
+		Pending:  t.BlockNumber == nil,
+	}
+	if t.tx.To() != nil {
+		tx.To = t.tx.To().Hex()
+	}
+	if t.tx.Type() == types.DynamicFeeTxType {
+		tx.GasTipCap = t.tx.GasTipCap().String()
+		tx.GasFeeCap = t.tx.GasFeeCap().String()
+	}
+	// nodes may leave block fields out, they stay unset then
+	if t.BlockNumber != nil {
+		number := t.BlockNumber.ToInt().Uint64()
+		tx.BlockNumber = &number
+	}
+	if t.BlockHash != nil {
+		hash := t.BlockHash.Hex()
+		tx.BlockHash = &hash
+	}
+	if t.TxIndex != nil {
+		index := uint64(*t.TxIndex)
+		tx.TxIndex = &index
+	}
+	return tx
+}
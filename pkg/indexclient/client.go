@@ -0,0 +1,301 @@
+package indexclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ethbaas/internal/config"
+	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
+	"ethbaas/internal/log"
+	"ethbaas/pkg/projclient"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type Client struct {
+	db  *db.Client
+	sel *ethcomm.Selector
+}
+
+func NewClient(db *db.Client) *Client {
+	c := &Client{
+		db:  db,
+		sel: ethcomm.DefaultSelector,
+	}
+	return c
+}
+
+// registered contract of an indexed address
+type indexedContract struct {
+	name string
+	abi  abi.ABI
+}
+
+// follow the chain head of projName and index blocks from fromBlock until
+// ctx is done; an interrupted indexer resumes after its last block
+func (c *Client) Run(ctx context.Context, projName string, fromBlock uint64) error {
+	dbproj, err := c.db.GetProject(projName)
+	if err != nil {
+		return err
+	}
+
+	config.C.SetDefault("indexer.pollinterval", "2s")
+	ticker := time.NewTicker(config.C.GetDuration("indexer.pollinterval"))
+	defer ticker.Stop()
+	for {
+		if err := c.index(ctx, dbproj, fromBlock); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Logger.Warnf("Indexing %s failed: %s", projName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// index up to indexer.batch blocks towards the head
+func (c *Client) index(ctx context.Context, dbproj *db.Project, fromBlock uint64) error {
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), ethcomm.AnyNode)
+	if err != nil {
+		return err
+	}
+	chainId, err := conn.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := conn.EthCli.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	contracts, err := c.contracts(dbproj.Name)
+	if err != nil {
+		return err
+	}
+
+	config.C.SetDefault("indexer.batch", 100)
+	batch := config.C.GetInt("indexer.batch")
+	for i := 0; i < batch; i++ {
+		state, err := c.db.GetIndexerState(dbproj.Name)
+		if err != nil {
+			return err
+		}
+		next := fromBlock
+		if state != nil {
+			next = state.Number + 1
+		}
+
+		if next > head {
+			// the tip may still be orphaned when no block follows it
+			if state == nil {
+				return nil
+			}
+			orphaned, err := tipOrphaned(ctx, conn.EthCli, head, state)
+			if err != nil || !orphaned {
+				return err
+			}
+			if err := c.rollback(dbproj.Name, state.Number); err != nil {
+				return err
+			}
+			continue
+		}
+
+		block, err := conn.EthCli.BlockByNumber(ctx, new(big.Int).SetUint64(next))
+		if err != nil {
+			return err
+		}
+		if state != nil && block.ParentHash().Hex() != state.Hash {
+			if err := c.rollback(dbproj.Name, state.Number); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.save(ctx, conn, chainId, dbproj.Name, block, contracts); err != nil {
+			return err
+		}
+		if next%100 == 0 || next == head {
+			log.Logger.Infof("Indexed %s block %d of %d", dbproj.Name, next, head)
+		}
+	}
+	return nil
+}
+
+type headerReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// the indexed tip was reorganized out when the node has another block at its
+// height; a node lagging behind the tip, e.g. picked among nodes within
+// rpc.maxlag, has not seen the height yet and tells nothing
+func tipOrphaned(ctx context.Context, chain headerReader, head uint64, state *db.IndexerState) (bool, error) {
+	if head < state.Number {
+		return false, nil
+	}
+	tip, err := chain.HeaderByNumber(ctx, new(big.Int).SetUint64(state.Number))
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return tip.Hash().Hex() != state.Hash, nil
+}
+
+func (c *Client) rollback(projName string, number uint64) error {
+	log.Logger.Warnf("Block %d of %s was reorganized out, rolling back", number, projName)
+	return c.db.RollbackBlocks(projName, number, time.Now().Unix())
+}
+
+// persist block with its txs, receipts and logs
+func (c *Client) save(ctx context.Context, conn *ethcomm.ChainConn, chainId *big.Int, projName string, block *types.Block, contracts map[common.Address]*indexedContract) error {
+	dbblock := &db.Block{
+		Proj:       projName,
+		Number:     block.NumberU64(),
+		Hash:       block.Hash().Hex(),
+		ParentHash: block.ParentHash().Hex(),
+		Time:       block.Time(),
+		Miner:      block.Coinbase().Hex(),
+		GasUsed:    block.GasUsed(),
+		GasLimit:   block.GasLimit(),
+		TxCount:    len(block.Transactions()),
+	}
+	if block.BaseFee() != nil {
+		dbblock.BaseFee = block.BaseFee().String()
+	}
+
+	signer := types.LatestSignerForChainID(chainId)
+	txs := []db.ChainTx{}
+	logs := []db.ChainLog{}
+	for i, tx := range block.Transactions() {
+		receipt, err := conn.EthCli.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return err
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		dbtx := db.ChainTx{
+			Proj:        projName,
+			Hash:        tx.Hash().Hex(),
+			BlockNumber: dbblock.Number,
+			BlockHash:   dbblock.Hash,
+			TxIndex:     uint(i),
+			Type:        tx.Type(),
+			From:        from.Hex(),
+			Value:       tx.Value().String(),
+			Nonce:       tx.Nonce(),
+			Gas:         tx.Gas(),
+			GasPrice:    tx.GasPrice().String(),
+			Input:       hexutil.Encode(tx.Data()),
+			Status:      receipt.Status,
+			GasUsed:     receipt.GasUsed,
+			LogCount:    len(receipt.Logs),
+		}
+		if tx.To() != nil {
+			dbtx.To = tx.To().Hex()
+		}
+		dbtx.EffectiveGas = effectiveGasPrice(tx, block.BaseFee()).String()
+		if receipt.ContractAddress != (common.Address{}) {
+			dbtx.ContractAddress = receipt.ContractAddress.Hex()
+		}
+		txs = append(txs, dbtx)
+
+		for _, l := range receipt.Logs {
+			dblog, err := newLog(projName, l, contracts)
+			if err != nil {
+				return err
+			}
+			logs = append(logs, *dblog)
+		}
+	}
+	return c.db.SaveBlock(dbblock, txs, logs, time.Now().Unix())
+}
+
+// price paid per gas, the fee cap bounds base fee plus tip on dynamic fee txs
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil || tx.Type() == types.LegacyTxType {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return tx.GasFeeCap()
+	}
+	return price
+}
+
+// log decoded with the abi of the registered contract emitting it
+func newLog(projName string, l *types.Log, contracts map[common.Address]*indexedContract) (*db.ChainLog, error) {
+	topics := make([]string, len(l.Topics))
+	for i, t := range l.Topics {
+		topics[i] = t.Hex()
+	}
+	dblog := &db.ChainLog{
+		Proj:        projName,
+		BlockNumber: l.BlockNumber,
+		BlockHash:   l.BlockHash.Hex(),
+		TxHash:      l.TxHash.Hex(),
+		LogIndex:    l.Index,
+		Address:     l.Address.Hex(),
+		Topics:      strings.Join(topics, ","),
+		Data:        hexutil.Encode(l.Data),
+	}
+
+	contract, ok := contracts[l.Address]
+	if !ok {
+		return dblog, nil
+	}
+	dblog.Contract = contract.name
+	event, ok, err := ethcomm.DecodeLog(&contract.abi, *l, false)
+	if err != nil {
+		// a log not matching the registered abi is kept undecoded
+		log.Logger.Warn(err)
+		return dblog, nil
+	}
+	if !ok {
+		return dblog, nil
+	}
+	args, err := json.Marshal(event.Args)
+	if err != nil {
+		return nil, err
+	}
+	dblog.Event = event.Event
+	dblog.Args = string(args)
+	return dblog, nil
+}
+
+// registered contracts of the project by address, the latest version wins
+// when an address was registered several times
+func (c *Client) contracts(projName string) (map[common.Address]*indexedContract, error) {
+	list, err := c.db.ListProjectContracts(projName)
+	if err != nil {
+		return nil, err
+	}
+	contracts := map[common.Address]*indexedContract{}
+	versions := map[common.Address]int{}
+	for _, item := range list {
+		addr := common.HexToAddress(item.Address)
+		if v, ok := versions[addr]; ok && v > item.Version {
+			continue
+		}
+		contractAbi, err := abi.JSON(strings.NewReader(item.ABI))
+		if err != nil {
+			log.Logger.Warnf("Invalid abi of contract %s version %d: %s", item.Name, item.Version, err)
+			continue
+		}
+		contracts[addr] = &indexedContract{name: item.Name, abi: contractAbi}
+		versions[addr] = item.Version
+	}
+	return contracts, nil
+}
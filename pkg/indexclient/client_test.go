@@ -0,0 +1,62 @@
+package indexclient
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/db"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// chain of a node knowing headers up to its head
+type testChain struct {
+	headers map[uint64]*types.Header
+	err     error
+}
+
+func (c *testChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	h, ok := c.headers[number.Uint64()]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return h, nil
+}
+
+func TestTipOrphaned(t *testing.T) {
+	tip := &types.Header{Number: big.NewInt(10), Extra: []byte("a")}
+	fork := &types.Header{Number: big.NewInt(10), Extra: []byte("b")}
+	state := &db.IndexerState{Number: 10, Hash: tip.Hash().Hex()}
+	tests := []struct {
+		name  string
+		chain *testChain
+		head  uint64
+		want  bool
+	}{
+		{"same tip", &testChain{headers: map[uint64]*types.Header{10: tip}}, 10, false},
+		{"tip replaced", &testChain{headers: map[uint64]*types.Header{10: fork}}, 10, true},
+		{"lagging node", &testChain{headers: map[uint64]*types.Header{}}, 8, false},
+		{"height not found", &testChain{headers: map[uint64]*types.Header{}}, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tipOrphaned(context.Background(), tt.chain, tt.head, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	failing := &testChain{err: errors.New("connection refused")}
+	if _, err := tipOrphaned(context.Background(), failing, 10, state); err == nil {
+		t.Error("node error not returned")
+	}
+}
@@ -2,11 +2,12 @@ package projclient
 
 import (
 	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
 	"ethbaas/internal/k8s"
+	"ethbaas/internal/log"
 	"ethbaas/internal/model"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"time"
@@ -31,7 +32,26 @@ func (c *Client) Init(p *model.Project) error {
 		return fmt.Errorf("Project %s already exist.", p.Name)
 	}
 
+	// http ports follow the first node port, ws ports are the next free ones
+	used, err := c.usedPorts(p.Name)
+	if err != nil {
+		return err
+	}
+	p.Ports = []int32{}
+	for i := 0; i < p.NodeCount; i++ {
+		p.Ports = append(p.Ports, p.FirstNodePort+int32(i))
+	}
+	if err := checkPorts(used, p.Name, p.Ports); err != nil {
+		return err
+	}
+	p.WsPorts, err = freePorts(used, p.Name, p.FirstNodePort+int32(p.NodeCount), p.NodeCount)
+	if err != nil {
+		return err
+	}
+
+	// files of a failed init would be applied by a later project of the name
 	if err := c.parser.Parse(p); err != nil {
+		os.RemoveAll(p.Home())
 		return err
 	}
 	dbProj := &db.Project{
@@ -39,9 +59,11 @@ func (c *Client) Init(p *model.Project) error {
 		NodeCount: p.NodeCount,
 		Created:   time.Now().Unix(),
 		NodePort:  p.Port2Str(),
+		WsPort:    p.WsPort2Str(),
 	}
 
 	if err := c.db.AddProject(dbProj); err != nil {
+		os.RemoveAll(p.Home())
 		return err
 	}
 
@@ -67,7 +89,7 @@ func (c *Client) Start(projName string) error {
 
 	info, err := ioutil.ReadDir(proj.Home())
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	yamlFiles := []string{}
@@ -77,9 +99,11 @@ func (c *Client) Start(projName string) error {
 			yamlFiles = append(yamlFiles, full)
 		}
 	}
-	for _, p := range yamlFiles {
+	for i, p := range yamlFiles {
 		err := k8s.Apply(p)
 		if err != nil {
+			// a project not marked running must not leave resources behind
+			deleteFiles(yamlFiles[:i])
 			return err
 		}
 	}
@@ -89,6 +113,9 @@ func (c *Client) Start(projName string) error {
 		return err
 	}
 
+	if err := c.SyncWsPorts(projName); err != nil {
+		log.Logger.Warnf("Read ws ports of %s failed: %s", projName, err)
+	}
 	return nil
 }
 
@@ -116,10 +143,11 @@ func (c *Client) Stop(projName string) error {
 			yamlFiles = append(yamlFiles, full)
 		}
 	}
+	// stays running until all resources are deleted, stop can be retried
 	for _, p := range yamlFiles {
 		err := k8s.Delete(p)
 		if err != nil {
-			return nil
+			return err
 		}
 	}
 
@@ -158,6 +186,21 @@ func (c *Client) Get(projName string) (*db.Project, error) {
 	return c.db.GetProject(projName)
 }
 
+// chain nodes of project
+func Nodes(p *db.Project) []ethcomm.Node {
+	ports := p.Str2Port()
+	wsPorts := p.Str2WsPort()
+	nodes := []ethcomm.Node{}
+	for i, port := range ports {
+		node := ethcomm.Node{Proj: p.Name, Index: i, Port: port}
+		if i < len(wsPorts) {
+			node.WsPort = wsPorts[i]
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
 func (c *Client) GetInModel(projName string) (*model.Project, error) {
 	dbProj, err := c.Get(projName)
 	if err != nil {
@@ -167,6 +210,163 @@ func (c *Client) GetInModel(projName string) (*model.Project, error) {
 		Name:          dbProj.Name,
 		NodeCount:     dbProj.NodeCount,
 		FirstNodePort: dbProj.Str2Port()[0],
+		Ports:         dbProj.Str2Port(),
+		WsPorts:       dbProj.Str2WsPort(),
 	}
 	return m, nil
 }
+
+// scale project to nodeCount nodes. existing nodes keep their ports, added
+// nodes get free ports after the highest one; on a running project removed
+// nodes are deleted and added ones applied. the project is saved after each
+// node so that a failure leaves it matching its files and k8s resources
+func (c *Client) Scale(projName string, nodeCount int) error {
+	if nodeCount < 1 {
+		return fmt.Errorf("Node count must be at least 1.")
+	}
+	// ws ports k8s assigned to nodes of older projects are kept, not guessed
+	if err := c.SyncWsPorts(projName); err != nil {
+		return err
+	}
+	dbproj, err := c.db.GetProject(projName)
+	if err != nil {
+		return err
+	}
+	proj, err := c.GetInModel(projName)
+	if err != nil {
+		return err
+	}
+	if nodeCount == proj.NodeCount {
+		return fmt.Errorf("Project %s already has %d nodes.", projName, nodeCount)
+	}
+
+	// pin ports of existing nodes, ws ports otherwise shift with the count
+	used, err := c.usedPorts(projName)
+	if err != nil {
+		return err
+	}
+	ports, wsPorts := []int32{}, []int32{}
+	next := int32(0)
+	for i := 0; i < proj.NodeCount; i++ {
+		ports = append(ports, proj.NodePort(i))
+		wsPorts = append(wsPorts, proj.WsNodePort(i))
+		used[proj.NodePort(i)], used[proj.WsNodePort(i)] = projName, projName
+		if proj.NodePort(i) >= next {
+			next = proj.NodePort(i) + 1
+		}
+		if proj.WsNodePort(i) >= next {
+			next = proj.WsNodePort(i) + 1
+		}
+	}
+	for i := proj.NodeCount; i < nodeCount; i++ {
+		port, err := freePorts(used, projName, next, 1)
+		if err != nil {
+			return err
+		}
+		wsPort, err := freePorts(used, projName, port[0]+1, 1)
+		if err != nil {
+			return err
+		}
+		ports, wsPorts = append(ports, port[0]), append(wsPorts, wsPort[0])
+		next = wsPort[0] + 1
+	}
+	oldCount := proj.NodeCount
+	proj.Ports, proj.WsPorts = ports, wsPorts
+
+	// removed nodes, in reverse order of creation
+	for i := oldCount - 1; i >= nodeCount; i-- {
+		files := nodeFiles(proj, i)
+		if dbproj.Running {
+			if err := deleteFiles(files); err != nil {
+				return err
+			}
+		}
+		if err := removeFiles(files); err != nil {
+			return err
+		}
+		if err := c.saveNodes(dbproj, proj, i); err != nil {
+			return err
+		}
+	}
+
+	if nodeCount < oldCount {
+		return nil
+	}
+	proj.NodeCount = nodeCount
+	added := []int{}
+	for i := oldCount; i < nodeCount; i++ {
+		added = append(added, i)
+	}
+	if err := c.parser.ParseNodes(proj, added...); err != nil {
+		removeNodes(proj, oldCount, nodeCount)
+		return err
+	}
+	for i := oldCount; i < nodeCount; i++ {
+		if dbproj.Running {
+			if err := applyFiles(nodeFiles(proj, i)); err != nil {
+				removeNodes(proj, i, nodeCount)
+				return err
+			}
+		}
+		if err := c.saveNodes(dbproj, proj, i+1); err != nil {
+			if dbproj.Running {
+				deleteFiles(nodeFiles(proj, i))
+			}
+			removeNodes(proj, i, nodeCount)
+			return err
+		}
+	}
+	return nil
+}
+
+// save the first n nodes of proj as the nodes of dbproj
+func (c *Client) saveNodes(dbproj *db.Project, proj *model.Project, n int) error {
+	saved := *proj
+	saved.NodeCount = n
+	dbproj.NodeCount = n
+	dbproj.NodePort = saved.Port2Str()
+	dbproj.WsPort = saved.WsPort2Str()
+	return c.db.UpdateProject(dbproj)
+}
+
+// yaml files of node i, in the order they are applied
+func nodeFiles(proj *model.Project, i int) []string {
+	return []string{proj.PvFile(i), proj.PvcFile(i), proj.DeployFile(i), proj.SvcFile(i)}
+}
+
+// apply files in order, resources of a failed node are deleted again
+func applyFiles(files []string) error {
+	for i, f := range files {
+		if err := k8s.Apply(f); err != nil {
+			deleteFiles(files[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// delete resources of files in reverse order of applying them
+func deleteFiles(files []string) error {
+	for i := len(files) - 1; i >= 0; i-- {
+		if err := k8s.Delete(files[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeFiles(files []string) error {
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove files of nodes from to to, which were not added
+func removeNodes(proj *model.Project, from, to int) {
+	for i := from; i < to; i++ {
+		removeFiles(nodeFiles(proj, i))
+	}
+}
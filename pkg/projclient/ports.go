@@ -0,0 +1,114 @@
+package projclient
+
+import (
+	"errors"
+	"ethbaas/internal/k8s"
+	"fmt"
+)
+
+// k8s NodePort range
+const (
+	MinNodePort = 30000
+	MaxNodePort = 32767
+)
+
+var (
+	ErrPortRange = errors.New("Node port out of range")
+	ErrPortInUse = errors.New("Node port in use")
+)
+
+// node ports in use by projects other than except, mapped to their project
+func (c *Client) usedPorts(except string) (map[int32]string, error) {
+	_, list, err := c.db.ListProject()
+	if err != nil {
+		return nil, err
+	}
+	used := map[int32]string{}
+	for _, p := range list {
+		if p.Name == except {
+			continue
+		}
+		for _, port := range append(p.Str2Port(), p.Str2WsPort()...) {
+			// unknown ws port of a project created before they were allocated
+			if port != 0 {
+				used[port] = p.Name
+			}
+		}
+	}
+	return used, nil
+}
+
+// check ports are in the NodePort range and not used, and mark them used by proj
+func checkPorts(used map[int32]string, proj string, ports []int32) error {
+	for _, port := range ports {
+		if port < MinNodePort || port > MaxNodePort {
+			return fmt.Errorf("%w: %d not in %d-%d", ErrPortRange, port, MinNodePort, MaxNodePort)
+		}
+		if owner, ok := used[port]; ok {
+			return fmt.Errorf("%w: %d by project %s", ErrPortInUse, port, owner)
+		}
+		used[port] = proj
+	}
+	return nil
+}
+
+// allocate n free ports from start for proj
+func freePorts(used map[int32]string, proj string, start int32, n int) ([]int32, error) {
+	ports := []int32{}
+	for port := start; len(ports) < n; port++ {
+		if port > MaxNodePort {
+			return nil, fmt.Errorf("%w: no free port left after %d", ErrPortRange, start)
+		}
+		if _, ok := used[port]; ok || port < MinNodePort {
+			continue
+		}
+		used[port] = proj
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// ws node ports of projects created before they were allocated were assigned
+// by k8s. read them from the services of the running project once, store them
+// and pin them in the svc yaml so that restarts keep them
+func (c *Client) SyncWsPorts(projName string) error {
+	dbproj, err := c.db.GetProject(projName)
+	if err != nil {
+		return err
+	}
+	proj, err := c.GetInModel(projName)
+	if err != nil {
+		return err
+	}
+	missing := []int{}
+	for i := 0; i < proj.NodeCount; i++ {
+		if proj.WsNodePort(i) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 || !dbproj.Running {
+		return nil
+	}
+
+	svcPorts, err := k8s.SvcNodePorts(proj.NS(), k8s.WsPortName)
+	if err != nil {
+		return err
+	}
+	wsPorts := []int32{}
+	for i := 0; i < proj.NodeCount; i++ {
+		wsPorts = append(wsPorts, proj.WsNodePort(i))
+	}
+	proj.WsPorts = wsPorts
+	for _, i := range missing {
+		port, ok := svcPorts[fmt.Sprintf("node%d", i)]
+		if !ok {
+			return fmt.Errorf("Service of node %d of %s not found", i, projName)
+		}
+		wsPorts[i] = port
+		if err := c.parser.ParseSvc(proj, i); err != nil {
+			return err
+		}
+	}
+	dbproj.WsPort = proj.WsPort2Str()
+	return c.db.UpdateProject(dbproj)
+}
@@ -0,0 +1,431 @@
+package txclient
+
+import (
+	"context"
+	"errors"
+	"ethbaas/internal/db"
+	"ethbaas/internal/ethcomm"
+	"ethbaas/internal/log"
+	"ethbaas/internal/model"
+	"ethbaas/pkg/projclient"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+type Client struct {
+	db  *db.Client
+	sel *ethcomm.Selector
+}
+
+func NewClient(db *db.Client) *Client {
+	c := &Client{
+		db:  db,
+		sel: ethcomm.DefaultSelector,
+	}
+	return c
+}
+
+// record a sent tx with its result
+func (c *Client) Record(projName, contract, method, args string, tx *types.Transaction, res *model.TxResult) error {
+	dbtx, err := newRecord(projName, contract, method, args, tx)
+	if err != nil {
+		return err
+	}
+	applyResult(dbtx, res)
+	if err := c.db.AddTransaction(dbtx); err != nil {
+		return err
+	}
+	return c.settleDeploy(dbtx)
+}
+
+// record a sent deployment, its contract is registered once the tx is mined
+func (c *Client) RecordDeploy(deploy *db.PendingDeploy, args string, tx *types.Transaction, res *model.TxResult) error {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return err
+	}
+	deploy.Sender, deploy.Nonce = from.Hex(), tx.Nonce()
+	if err := c.db.AddPendingDeploy(deploy); err != nil {
+		return err
+	}
+	return c.Record(deploy.Proj, deploy.Name, "constructor", args, tx, res)
+}
+
+// record a sent tx, failing to record is only logged since the tx is
+// already broadcast
+func (c *Client) Track(projName, contract, method, args string, tx *types.Transaction, res *model.TxResult) {
+	if err := c.Record(projName, contract, method, args, tx, res); err != nil {
+		log.Logger.Warnf("Record tx %s failed: %s", tx.Hash().Hex(), err)
+	}
+}
+
+// list recorded txs, empty proj or status matches all
+func (c *Client) List(projName, status string) (int64, []db.Transaction, error) {
+	return c.db.ListTransaction(projName, status)
+}
+
+func (c *Client) Get(hash string) (*db.Transaction, error) {
+	return c.db.GetTransaction(common.HexToHash(hash).Hex())
+}
+
+// wait until tx is mined and update its record
+func (c *Client) Wait(ctx context.Context, hash string) (*db.Transaction, error) {
+	dbtx, err := c.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+	if dbtx.Status != db.TxPending {
+		return dbtx, nil
+	}
+	dbproj, err := c.db.GetProject(dbtx.Proj)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := decodeRaw(dbtx.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), ethcomm.AnyNode)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ethcomm.Outcome(ctx, conn, tx, true)
+	if res == nil || !res.Mined {
+		return nil, err
+	}
+	applyResult(dbtx, res)
+	if err := c.save(dbtx); err != nil {
+		return nil, err
+	}
+	return dbtx, nil
+}
+
+// broadcast the signed tx of a pending record again
+func (c *Client) Resend(ctx context.Context, hash string) error {
+	dbtx, err := c.Get(hash)
+	if err != nil {
+		return err
+	}
+	if dbtx.Status != db.TxPending && dbtx.Status != db.TxDropped {
+		return fmt.Errorf("Transaction %s is %s, only pending or dropped txs can be resent", dbtx.Hash, dbtx.Status)
+	}
+	dbproj, err := c.db.GetProject(dbtx.Proj)
+	if err != nil {
+		return err
+	}
+	tx, err := decodeRaw(dbtx.Raw)
+	if err != nil {
+		return err
+	}
+
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), ethcomm.AnyNode)
+	if err != nil {
+		return err
+	}
+	err = conn.EthCli.SendTransaction(ctx, tx)
+	if err != nil && !ethcomm.IsKnownTxError(err) {
+		return err
+	}
+	dbtx.Status = db.TxPending
+	dbtx.Updated = time.Now().Unix()
+	return c.db.UpdateTransaction(dbtx)
+}
+
+// resubmit a pending tx with the same nonce and bumped fees
+func (c *Client) Speedup(ctx context.Context, hash string) (*db.Transaction, error) {
+	return c.replace(ctx, hash, false)
+}
+
+// replace a pending tx by a zero value transfer to its sender
+func (c *Client) Cancel(ctx context.Context, hash string) (*db.Transaction, error) {
+	return c.replace(ctx, hash, true)
+}
+
+func (c *Client) replace(ctx context.Context, hash string, cancel bool) (*db.Transaction, error) {
+	dbtx, err := c.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+	if dbtx.Status != db.TxPending {
+		return nil, fmt.Errorf("Transaction %s is %s, only pending txs can be replaced", dbtx.Hash, dbtx.Status)
+	}
+	dbproj, err := c.db.GetProject(dbtx.Proj)
+	if err != nil {
+		return nil, err
+	}
+	old, err := decodeRaw(dbtx.Raw)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := loadSigner(dbtx.From)
+	if err != nil {
+		return nil, err
+	}
+
+	_, conn, err := c.sel.Pick(ctx, projclient.Nodes(dbproj), ethcomm.AnyNode)
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := conn.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := ethcomm.GenReplaceOpts(ctx, conn, signer, old)
+	if err != nil {
+		return nil, err
+	}
+
+	to, data := old.To(), old.Data()
+	auth.Value, auth.GasLimit = old.Value(), old.Gas()
+	method, args := dbtx.Method, dbtx.Args
+	if cancel {
+		from := signer.Address()
+		to, data = &from, nil
+		auth.Value, auth.GasLimit = big.NewInt(0), params.TxGas
+		method, args = "cancel", dbtx.Hash
+	}
+	tx, err := signer.SignTx(ctx, ethcomm.NewTx(auth, chainId, old.Nonce(), to, data), chainId)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.EthCli.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	newtx, err := newRecord(dbtx.Proj, dbtx.Contract, method, args, tx)
+	if err != nil {
+		return nil, err
+	}
+	newtx.Replaces = dbtx.Hash
+	if err := c.db.AddTransaction(newtx); err != nil {
+		return nil, err
+	}
+	dbtx.Status = db.TxReplaced
+	dbtx.Updated = time.Now().Unix()
+	return newtx, c.db.UpdateTransaction(dbtx)
+}
+
+// resolve pending txs, returns the number of txs updated
+func (c *Client) Refresh(ctx context.Context) (int, error) {
+	_, list, err := c.db.ListTransaction("", db.TxPending)
+	if err != nil {
+		return 0, err
+	}
+	updated := 0
+	for i := range list {
+		dbtx := &list[i]
+		changed, err := c.refresh(ctx, dbtx)
+		if err != nil {
+			log.Logger.Warnf("Refresh tx %s failed: %s", dbtx.Hash, err)
+			continue
+		}
+		if changed {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// refresh pending txs periodically until ctx is done
+func (c *Client) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := c.Refresh(ctx); err != nil {
+			log.Logger.Warn("Refresh txs failed: ", err)
+		} else if n > 0 {
+			log.Logger.Infof("%d txs resolved", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) refresh(ctx context.Context, dbtx *db.Transaction) (bool, error) {
+	dbproj, err := c.db.GetProject(dbtx.Proj)
+	if err != nil {
+		return false, err
+	}
+	tx, err := decodeRaw(dbtx.Raw)
+	if err != nil {
+		return false, err
+	}
+
+	var receipt *types.Receipt
+	var nonce uint64
+	var res *model.TxResult
+	err = c.sel.Read(ctx, projclient.Nodes(dbproj), ethcomm.AnyNode, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		receipt, err = conn.EthCli.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			res = ethcomm.NewTxResult(ctx, conn, tx, receipt)
+			return nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return err
+		}
+		nonce, err = conn.EthCli.NonceAt(ctx, common.HexToAddress(dbtx.From), nil)
+		if err != nil || nonce <= dbtx.Nonce {
+			return err
+		}
+		// the tx may have been mined between both queries
+		receipt, err = conn.EthCli.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			res = ethcomm.NewTxResult(ctx, conn, tx, receipt)
+			return nil
+		}
+		if errors.Is(err, ethereum.NotFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if res == nil {
+		// nonce used by another tx, this one will never be mined
+		if nonce > dbtx.Nonce {
+			dbtx.Status = db.TxDropped
+			dbtx.Updated = time.Now().Unix()
+			if dbtx.Replaces != "" {
+				c.resolveReplaced(ctx, projclient.Nodes(dbproj), dbtx.Replaces)
+			}
+			return true, c.save(dbtx)
+		}
+		return false, nil
+	}
+	applyResult(dbtx, res)
+	return true, c.save(dbtx)
+}
+
+// a replacement was dropped, the tx it replaced may have been mined instead
+func (c *Client) resolveReplaced(ctx context.Context, nodes []ethcomm.Node, hash string) {
+	dbtx, err := c.Get(hash)
+	if err != nil || dbtx.Status != db.TxReplaced {
+		return
+	}
+	tx, err := decodeRaw(dbtx.Raw)
+	if err != nil {
+		return
+	}
+	var res *model.TxResult
+	err = c.sel.Read(ctx, nodes, ethcomm.AnyNode, func(ctx context.Context, conn *ethcomm.ChainConn) error {
+		receipt, err := conn.EthCli.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return err
+		}
+		res = ethcomm.NewTxResult(ctx, conn, tx, receipt)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	applyResult(dbtx, res)
+	if err := c.save(dbtx); err != nil {
+		log.Logger.Warnf("Update tx %s failed: %s", dbtx.Hash, err)
+	}
+}
+
+// update a tx record, settling the deployment pending on its nonce
+func (c *Client) save(dbtx *db.Transaction) error {
+	if err := c.db.UpdateTransaction(dbtx); err != nil {
+		return err
+	}
+	return c.settleDeploy(dbtx)
+}
+
+// once a tx is mined the deployment pending on its nonce is registered when
+// it is a successful deployment and discarded otherwise, e.g. reverted or
+// cancelled
+func (c *Client) settleDeploy(dbtx *db.Transaction) error {
+	if dbtx.Status != db.TxSuccess && dbtx.Status != db.TxFailed {
+		return nil
+	}
+	deploy, err := c.db.GetPendingDeploy(dbtx.Proj, dbtx.From, dbtx.Nonce)
+	if err != nil || deploy == nil {
+		return err
+	}
+	address := ""
+	if dbtx.Status == db.TxSuccess && dbtx.Method == "constructor" {
+		address = dbtx.ContractAddress
+	}
+	return c.db.ResolvePendingDeploy(deploy, address)
+}
+
+// signer of from, falls back to the default signer when it is the same
+// account, e.g. txs sent with the deprecated adminpk
+func loadSigner(from string) (ethcomm.Signer, error) {
+	signer, err := ethcomm.LoadSigner(from)
+	if err == nil {
+		return signer, nil
+	}
+	if def, derr := ethcomm.LoadSigner(""); derr == nil && def.Address() == common.HexToAddress(from) {
+		return def, nil
+	}
+	return nil, err
+}
+
+// pending record of a sent tx
+func newRecord(projName, contract, method, args string, tx *types.Transaction) (*db.Transaction, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	dbtx := &db.Transaction{
+		Hash:     tx.Hash().Hex(),
+		Proj:     projName,
+		Contract: contract,
+		Method:   method,
+		Args:     args,
+		From:     from.Hex(),
+		Nonce:    tx.Nonce(),
+		Raw:      hexutil.Encode(raw),
+		Status:   db.TxPending,
+		Created:  now,
+		Updated:  now,
+	}
+	return dbtx, nil
+}
+
+func applyResult(dbtx *db.Transaction, res *model.TxResult) {
+	if res == nil || !res.Mined {
+		return
+	}
+	dbtx.Status = db.TxSuccess
+	if res.Failed() {
+		dbtx.Status = db.TxFailed
+	}
+	dbtx.BlockNumber = res.BlockNumber
+	dbtx.GasUsed = res.GasUsed
+	dbtx.ContractAddress = res.Contract
+	dbtx.RevertReason = res.RevertReason
+	dbtx.Updated = time.Now().Unix()
+}
+
+func decodeRaw(raw string) (*types.Transaction, error) {
+	b, err := hexutil.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	tx := &types.Transaction{}
+	if err := tx.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}